@@ -0,0 +1,287 @@
+package httpreq
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrUnsafeArchivePath is returned by ExtractArchive for an entry which would be extracted
+// outside of the destination directory (zip slip)
+var ErrUnsafeArchivePath = errors.New("archive entry path is outside of the destination directory")
+
+// ErrArchiveTooLarge is returned by ExtractArchive when the extracted files exceed the limit set by
+// Req.SetMaxExtractSize
+var ErrArchiveTooLarge = errors.New("extracted archive is too large")
+
+// ErrUnknownArchive is returned by ExtractArchive when the archive format can't be detected
+var ErrUnknownArchive = errors.New("unknown archive format")
+
+// archive formats supported by ExtractArchive
+const (
+	archiveZip = "zip"
+	archiveTar = "tar"
+	archiveTgz = "tar.gz"
+)
+
+// SetMaxExtractSize limits the total size of the files extracted by Response.ExtractArchive to maxBytes,
+// so small hostile archives (zip bombs) can't fill the disk. 0 means no limit.
+func (r *Req) SetMaxExtractSize(maxBytes int64) *Req {
+	r.maxExtractSize = maxBytes
+	return r
+}
+
+// ExtractArchive extracts the zip, tar or tar.gz body into destDir and returns the paths of the
+// extracted files. The format is detected from the Content-Type, then the filename as in Save.
+// All entries are checked before anything is written: entries resolving outside of destDir fail
+// with ErrUnsafeArchivePath, and with Req.SetMaxExtractSize, files exceeding it together fail with
+// ErrArchiveTooLarge. If extraction fails anyway, the files it created are removed, existing files
+// it overwrote are not restored. Links and other special entries are skipped.
+// The whole body is read into memory first.
+func (r *Response) ExtractArchive(destDir string) ([]string, error) {
+	format := r.archiveFormat()
+	if format == "" {
+		r.log().Errorf("Can't extract archive Error: %v", ErrUnknownArchive)
+		return nil, ErrUnknownArchive
+	}
+
+	body, err := r.Body()
+	if err != nil {
+		return nil, err
+	}
+
+	x := &extractor{destDir: destDir, maxBytes: r.maxExtractSize}
+	switch format {
+	case archiveZip:
+		err = x.zip(body)
+	default:
+		err = x.tar(func() (io.Reader, error) {
+			if format == archiveTgz {
+				return gzip.NewReader(bytes.NewReader(body))
+			}
+			return bytes.NewReader(body), nil
+		})
+	}
+
+	if err != nil {
+		r.log().Errorf("Can't extract archive to %s Error: %v", destDir, err)
+		for _, file := range x.created {
+			_ = os.Remove(file)
+		}
+		return nil, err
+	}
+	return x.files, nil
+}
+
+// archiveFormat detects the archive format of the body, or returns empty string
+func (r *Response) archiveFormat() string {
+	if mediaType, _, err := mime.ParseMediaType(r.Headers().Get("Content-Type")); err == nil {
+		switch mediaType {
+		case "application/zip", "application/x-zip-compressed":
+			return archiveZip
+		case "application/x-tar":
+			return archiveTar
+		case "application/gzip", "application/x-gzip", "application/x-gtar", "application/x-tgz":
+			return archiveTgz
+		}
+	}
+
+	name := strings.ToLower(r.filename())
+	switch {
+	case strings.HasSuffix(name, ".zip"):
+		return archiveZip
+	case strings.HasSuffix(name, ".tar"):
+		return archiveTar
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		return archiveTgz
+	}
+	return ""
+}
+
+// extractor extracts archive entries into destDir, limiting the total size of the files to maxBytes if positive
+type extractor struct {
+	destDir  string
+	maxBytes int64
+	written  int64
+
+	// files are the extracted files, created are the ones which didn't exist before
+	files   []string
+	created []string
+}
+
+// check validates the path of the entry name, adding its size to the declared total of regular
+// files, so the archive is rejected before anything is written. The real sizes are limited too
+// as the declared ones may lie.
+func (x *extractor) check(name string, size int64, regular bool, total *int64) error {
+	if _, err := archivePath(x.destDir, name); err != nil {
+		return err
+	}
+	if regular {
+		*total += size
+		if x.maxBytes > 0 && (size < 0 || *total > x.maxBytes) {
+			return ErrArchiveTooLarge
+		}
+	}
+	return nil
+}
+
+// zip extracts the zip archive data
+func (x *extractor) zip(data []byte) error {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return err
+	}
+
+	var total int64
+	for _, f := range zr.File {
+		if err = x.check(f.Name, int64(f.UncompressedSize64), f.Mode().IsRegular(), &total); err != nil {
+			return err
+		}
+	}
+
+	if err = os.MkdirAll(x.destDir, 0755); err != nil {
+		return err
+	}
+
+	for _, f := range zr.File {
+		target, err := archivePath(x.destDir, f.Name)
+		if err != nil {
+			return err
+		}
+
+		mode := f.Mode()
+		if mode.IsDir() {
+			if err = os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+			continue
+		}
+		if !mode.IsRegular() {
+			continue
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		err = x.writeFile(target, rc, mode.Perm())
+		_ = rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// tar extracts the tar archive read from the readers returned by open, once to check the entries
+// and once to extract them
+func (x *extractor) tar(open func() (io.Reader, error)) error {
+	r, err := open()
+	if err != nil {
+		return err
+	}
+
+	var total int64
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		regular := hdr.Typeflag == tar.TypeReg || hdr.Typeflag == tar.TypeRegA
+		if err = x.check(hdr.Name, hdr.Size, regular, &total); err != nil {
+			return err
+		}
+	}
+
+	if err = os.MkdirAll(x.destDir, 0755); err != nil {
+		return err
+	}
+
+	if r, err = open(); err != nil {
+		return err
+	}
+
+	tr = tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		target, err := archivePath(x.destDir, hdr.Name)
+		if err != nil {
+			return err
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err = os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg, tar.TypeRegA:
+			if err = x.writeFile(target, tr, os.FileMode(hdr.Mode).Perm()); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// archivePath returns the path of the archive entry name under destDir,
+// failing with ErrUnsafeArchivePath if it's outside of destDir
+func archivePath(destDir, name string) (string, error) {
+	target := filepath.Join(destDir, name)
+
+	rel, err := filepath.Rel(destDir, target)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) || filepath.IsAbs(name) {
+		return "", fmt.Errorf("%w: %s", ErrUnsafeArchivePath, name)
+	}
+
+	return target, nil
+}
+
+// writeFile writes an extracted file, creating its parent directories
+func (x *extractor) writeFile(target string, r io.Reader, perm os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+
+	if perm == 0 {
+		perm = 0644
+	}
+	_, statErr := os.Lstat(target)
+	f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	x.files = append(x.files, target)
+	if os.IsNotExist(statErr) {
+		x.created = append(x.created, target)
+	}
+
+	if x.maxBytes > 0 {
+		r = &limitedReader{r: r, n: x.maxBytes - x.written, err: ErrArchiveTooLarge}
+	}
+
+	n, err := io.Copy(f, r)
+	x.written += n
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,159 @@
+package httpreq
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtractArchive(t *testing.T) {
+	entries := map[string]string{
+		"a.txt":     "content of a",
+		"dir/b.txt": "content of b",
+	}
+
+	// Entries are written sorted by name
+	zipData := func(entries map[string]string) []byte {
+		names := make([]string, 0, len(entries))
+		for name := range entries {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		var buf bytes.Buffer
+		zw := zip.NewWriter(&buf)
+		for _, name := range names {
+			w, err := zw.Create(name)
+			require.NoError(t, err)
+			_, err = w.Write([]byte(entries[name]))
+			require.NoError(t, err)
+		}
+		require.NoError(t, zw.Close())
+		return buf.Bytes()
+	}
+
+	var tgz bytes.Buffer
+	zw := gzip.NewWriter(&tgz)
+	tw := tar.NewWriter(zw)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "dir/", Typeflag: tar.TypeDir, Mode: 0755}))
+	for name, content := range entries {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(content))}))
+		_, err := tw.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, zw.Close())
+
+	archives := map[string][]byte{
+		"/bomb.zip":     zipData(map[string]string{"a.txt": "content of a", "big.bin": strings.Repeat("\x00", 1<<20)}),
+		"/files.zip":    zipData(entries),
+		"/files.tar.gz": tgz.Bytes(),
+		"/partial.zip":  zipData(map[string]string{"a.txt": "content of a", "keep.txt": "new", "sub": "not a directory"}),
+		"/slip.zip":     zipData(map[string]string{"a.txt": "content of a", "dir/../../evil.txt": "evil"}),
+	}
+
+	// Start a local HTTP server serving archives as application/octet-stream
+	server := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			rw.Header().Set("Content-Type", "application/octet-stream")
+			_, _ = rw.Write(archives[req.URL.Path])
+		}),
+	)
+	defer server.Close()
+
+	for _, name := range []string{"/files.zip", "/files.tar.gz"} {
+		resp, err := New(context.Background(), server.URL+name).Get()
+		require.NoError(t, err)
+
+		dir := t.TempDir()
+		files, err := resp.ExtractArchive(dir)
+		require.NoError(t, err)
+		require.Len(t, files, len(entries))
+
+		for entry, content := range entries {
+			path := filepath.Join(dir, filepath.FromSlash(entry))
+			require.Contains(t, files, path)
+
+			data, err := ioutil.ReadFile(path)
+			require.NoError(t, err)
+			require.Equal(t, content, string(data))
+		}
+	}
+
+	// Entries outside of the destination directory are rejected before anything is written
+	resp, err := New(context.Background(), server.URL+"/slip.zip").Get()
+	require.NoError(t, err)
+
+	root := t.TempDir()
+	dir := filepath.Join(root, "dest")
+	files, err := resp.ExtractArchive(dir)
+	require.ErrorIs(t, err, ErrUnsafeArchivePath)
+	require.Empty(t, files)
+
+	_, err = os.Stat(filepath.Join(root, "evil.txt"))
+	require.True(t, os.IsNotExist(err))
+	_, err = os.Stat(dir)
+	require.True(t, os.IsNotExist(err))
+
+	// Extracted files are limited by SetMaxExtractSize before anything is written
+	resp, err = New(context.Background(), server.URL+"/bomb.zip").SetMaxExtractSize(64 << 10).Get()
+	require.NoError(t, err)
+
+	dir = t.TempDir()
+	files, err = resp.ExtractArchive(dir)
+	require.ErrorIs(t, err, ErrArchiveTooLarge)
+	require.Empty(t, files)
+
+	extracted, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	require.Empty(t, extracted)
+
+	// The maximum response size limits only the archive
+	resp, err = New(context.Background(), server.URL+"/bomb.zip").SetMaxResponseSize(64 << 10).Get()
+	require.NoError(t, err)
+	files, err = resp.ExtractArchive(t.TempDir())
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+
+	// Failed extractions remove only the files they created
+	dir = t.TempDir()
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "keep.txt"), []byte("keep"), 0600))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "sub"), 0755))
+
+	resp, err = New(context.Background(), server.URL+"/partial.zip").Get()
+	require.NoError(t, err)
+	_, err = resp.ExtractArchive(dir)
+	require.Error(t, err)
+
+	_, err = os.Stat(filepath.Join(dir, "a.txt"))
+	require.True(t, os.IsNotExist(err))
+	_, err = os.Stat(filepath.Join(dir, "keep.txt"))
+	require.NoError(t, err)
+
+	// The format is detected from the Content-Type
+	resp = NewResponse(&http.Response{
+		Header: http.Header{"Content-Type": []string{"application/zip"}},
+		Body:   ioutil.NopCloser(bytes.NewReader(zipData(entries))),
+	})
+	files, err = resp.ExtractArchive(t.TempDir())
+	require.NoError(t, err)
+	require.Len(t, files, len(entries))
+
+	// Unknown formats
+	resp, err = New(context.Background(), server.URL+"/files.rar").Get()
+	require.NoError(t, err)
+	_, err = resp.ExtractArchive(t.TempDir())
+	require.ErrorIs(t, err, ErrUnknownArchive)
+}
@@ -0,0 +1,135 @@
+package httpreq
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"sync/atomic"
+	"time"
+)
+
+// ErrBodyReadTimeout is returned when the server sends no body data for longer than SetMaxBodyReadTimeout
+var ErrBodyReadTimeout = errors.New("response body read timeout")
+
+// cancelBody wraps a response body to release the request context when the body is closed,
+// reads fail with the context error once the context is done
+type cancelBody struct {
+	io.ReadCloser
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err != nil && err != io.EOF && b.ctx.Err() != nil {
+		return n, b.ctx.Err()
+	}
+	return n, err
+}
+
+func (b *cancelBody) Close() error {
+	defer b.cancel()
+	return b.ReadCloser.Close()
+}
+
+// idleTimeoutBody fails a read blocked for longer than timeout by closing the wrapped body.
+// Time between reads isn't counted, so it bounds stalls of the server, not the total download time.
+type idleTimeoutBody struct {
+	io.ReadCloser
+	timer    *time.Timer
+	timeout  time.Duration
+	timedOut int32
+}
+
+func newIdleTimeoutBody(body io.ReadCloser, timeout time.Duration) *idleTimeoutBody {
+	b := &idleTimeoutBody{ReadCloser: body, timeout: timeout}
+	b.timer = time.AfterFunc(timeout, func() {
+		atomic.StoreInt32(&b.timedOut, 1)
+		_ = body.Close()
+	})
+	b.timer.Stop()
+	return b
+}
+
+func (b *idleTimeoutBody) Read(p []byte) (int, error) {
+	if atomic.LoadInt32(&b.timedOut) == 1 {
+		return 0, ErrBodyReadTimeout
+	}
+
+	b.timer.Reset(b.timeout)
+	n, err := b.ReadCloser.Read(p)
+	b.timer.Stop()
+
+	if atomic.LoadInt32(&b.timedOut) == 1 {
+		return n, ErrBodyReadTimeout
+	}
+	return n, err
+}
+
+func (b *idleTimeoutBody) Close() error {
+	b.timer.Stop()
+	return b.ReadCloser.Close()
+}
+
+// limitedReader reads from r and fails with err once more than n bytes are read,
+// unlike io.LimitReader larger bodies aren't silently truncated
+type limitedReader struct {
+	r   io.Reader
+	n   int64
+	err error
+}
+
+// limitBody limits body to maxBytes, reading more fails with ErrResponseTooLarge. 0 means no limit.
+func limitBody(body io.Reader, maxBytes int64) io.Reader {
+	if maxBytes <= 0 {
+		return body
+	}
+	return &limitedReader{r: body, n: maxBytes, err: ErrResponseTooLarge}
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.n < 0 {
+		return 0, l.err
+	}
+
+	// Read one more byte than allowed to detect larger bodies
+	if int64(len(p)) > l.n+1 {
+		p = p[:l.n+1]
+	}
+	n, err := l.r.Read(p)
+	if int64(n) > l.n {
+		n = int(l.n)
+		l.n = -1
+		return n, l.err
+	}
+	l.n -= int64(n)
+	return n, err
+}
+
+// readAllContext reads all of r, closing c to interrupt a blocked read when ctx is done
+func readAllContext(ctx context.Context, r io.Reader, c io.Closer) ([]byte, error) {
+	if ctx.Done() == nil {
+		return ioutil.ReadAll(r)
+	}
+
+	type result struct {
+		data []byte
+		err  error
+	}
+
+	// The goroutine returns once the read fails after closing
+	done := make(chan result, 1)
+	go func() {
+		data, err := ioutil.ReadAll(r)
+		done <- result{data: data, err: err}
+	}()
+
+	select {
+	case res := <-done:
+		return res.data, res.err
+	case <-ctx.Done():
+		_ = c.Close()
+		return nil, ctx.Err()
+	}
+}
@@ -0,0 +1,38 @@
+package httpreq
+
+import (
+	"github.com/fxamacker/cbor/v2"
+)
+
+// cborContentType is the media type of CBOR (RFC 8949)
+const cborContentType = "application/cbor"
+
+// SetBodyCBOR sets the request body to v marshaled as CBOR, a binary JSON alternative,
+// and sets the Content-Type and Accept headers to application/cbor
+func (r *Req) SetBodyCBOR(v interface{}) *Req {
+	data, err := cbor.Marshal(v)
+	if err != nil {
+		r.log().Errorf("Can't marshal CBOR body Error: %v", err)
+		r.setErr("SetBodyCBOR", err)
+		return r
+	}
+
+	r.SetContentType(cborContentType)
+	r.request.Header.Set("Accept", cborContentType)
+	return r.SetBody(data)
+}
+
+// DecodeCBOR unmarshals the CBOR response body into v
+func (r *Response) DecodeCBOR(v interface{}) error {
+	body, err := r.readBody()
+	if err != nil {
+		return err
+	}
+
+	if err = cbor.Unmarshal(body, v); err != nil {
+		r.log().Errorf("Can't decode CBOR response Error: %v", err)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,53 @@
+package httpreq
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCBOR(t *testing.T) {
+	type Item struct {
+		Name string
+		Data []byte
+		Tags map[string]int
+	}
+	expected := Item{Name: "item", Data: []byte{0, 1, 2}, Tags: map[string]int{"a": 1}}
+
+	// Start a local HTTP server which echoes the decoded body
+	server := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			require.Equal(t, "application/cbor", req.Header.Get("Content-Type"))
+			require.Equal(t, "application/cbor", req.Header.Get("Accept"))
+
+			body, err := ioutil.ReadAll(req.Body)
+			require.NoError(t, err)
+
+			var item Item
+			require.NoError(t, cbor.Unmarshal(body, &item))
+			require.Equal(t, expected, item)
+
+			rw.Header().Set("Content-Type", "application/cbor")
+			_, err = rw.Write(body)
+			require.NoError(t, err)
+		}),
+	)
+	defer server.Close()
+
+	resp, err := New(context.Background(), server.URL).SetBodyCBOR(expected).Post()
+	require.NoError(t, err)
+
+	var item Item
+	require.NoError(t, resp.DecodeCBOR(&item))
+	require.Equal(t, expected, item)
+
+	// Invalid values and bodies
+	require.Error(t, New(context.Background(), server.URL).SetBodyCBOR(make(chan int)).err)
+	require.Error(t, NewResponse(&http.Response{Body: ioutil.NopCloser(strings.NewReader("\xff"))}).DecodeCBOR(&item))
+}
@@ -0,0 +1,94 @@
+package httpreq
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"mime"
+	"strings"
+
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+// normalizeCharset lowercases the charset label and maps aliases to a canonical name
+func normalizeCharset(label string) string {
+	label = strings.ToLower(strings.TrimSpace(label))
+	switch label {
+	case "", "utf-8", "utf8":
+		return "utf-8"
+	case "iso-8859-1", "iso8859-1", "iso_8859-1", "latin1", "l1":
+		return "iso-8859-1"
+	case "us-ascii", "ascii":
+		return "us-ascii"
+	}
+	return label
+}
+
+// isUTF8Charset returns true if label is a charset that needs no transcoding to UTF-8
+func isUTF8Charset(label string) bool {
+	switch normalizeCharset(label) {
+	case "utf-8", "us-ascii":
+		return true
+	}
+	return false
+}
+
+// contentTypeCharset returns the charset parameter of the given Content-Type header value
+func contentTypeCharset(contentType string) string {
+	if contentType == "" {
+		return ""
+	}
+
+	_, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return ""
+	}
+
+	return params["charset"]
+}
+
+// toUTF8 transcodes data from the given charset to UTF-8.
+// Data in charsets unknown to the WHATWG encoding index is returned as is.
+func toUTF8(data []byte, label string) ([]byte, error) {
+	switch normalizeCharset(label) {
+	case "utf-8", "us-ascii":
+		return data, nil
+	case "iso-8859-1":
+		// ISO-8859-1 bytes map one to one to the first 256 unicode code points
+		b := make([]byte, 0, len(data))
+		for _, c := range data {
+			b = append(b, string(rune(c))...)
+		}
+		return b, nil
+	}
+
+	enc, err := htmlindex.Get(label)
+	if err != nil {
+		return data, nil
+	}
+	return enc.NewDecoder().Bytes(data)
+}
+
+// charsetReader is used as xml.Decoder.CharsetReader to transcode XML documents declaring a non UTF-8 encoding
+func charsetReader(label string, input io.Reader) (io.Reader, error) {
+	if isUTF8Charset(label) {
+		return input, nil
+	}
+
+	data, err := ioutil.ReadAll(input)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err = toUTF8(data, label)
+	if err != nil {
+		return nil, err
+	}
+
+	return bytes.NewReader(data), nil
+}
+
+// utf8Reader is used as xml.Decoder.CharsetReader when the document is already transcoded to UTF-8
+func utf8Reader(_ string, input io.Reader) (io.Reader, error) {
+	return input, nil
+}
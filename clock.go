@@ -0,0 +1,40 @@
+package httpreq
+
+import (
+	"context"
+	"time"
+)
+
+// clock provides the time for retries and measurements, so tests can replace it
+type clock interface {
+	// Now returns the current time
+	Now() time.Time
+
+	// Sleep waits for d or until ctx is done
+	Sleep(ctx context.Context, d time.Duration) error
+}
+
+// realClock is the clock of the time package
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) Sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// setClock replaces the clock of the request, for tests
+func (r *Req) setClock(c clock) *Req {
+	r.clock = c
+	return r
+}
@@ -0,0 +1,151 @@
+package httpreq
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"mime"
+	"strings"
+	"sync"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+// Codec marshals request bodies and unmarshals response bodies of a content type
+type Codec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// jsonCodec is the Codec of encoding/json
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+// xmlCodec is the Codec of encoding/xml
+type xmlCodec struct{}
+
+func (xmlCodec) Marshal(v interface{}) ([]byte, error) {
+	return xml.Marshal(v)
+}
+
+func (xmlCodec) Unmarshal(data []byte, v interface{}) error {
+	return xml.Unmarshal(data, v)
+}
+
+// cborCodec is the Codec of CBOR
+type cborCodec struct{}
+
+func (cborCodec) Marshal(v interface{}) ([]byte, error) {
+	return cbor.Marshal(v)
+}
+
+func (cborCodec) Unmarshal(data []byte, v interface{}) error {
+	return cbor.Unmarshal(data, v)
+}
+
+var (
+	codecsMu sync.RWMutex
+
+	// codecs are the codecs by media type
+	codecs = map[string]Codec{
+		"application/json": jsonCodec{},
+		"application/xml":  xmlCodec{},
+		"text/xml":         xmlCodec{},
+		cborContentType:    cborCodec{},
+	}
+)
+
+// RegisterCodec registers the codec used by SetBodyCodec and Response.Decode for contentType,
+// replacing the codec registered for it. JSON, XML and CBOR are registered by default.
+func RegisterCodec(contentType string, codec Codec) error {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return err
+	}
+
+	codecsMu.Lock()
+	defer codecsMu.Unlock()
+	codecs[mediaType] = codec
+	return nil
+}
+
+// lookupCodec returns the codec for the media type of contentType. Types with
+// a +json or +xml suffix like application/problem+json use the JSON or XML codec.
+func lookupCodec(contentType string) (Codec, error) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, err
+	}
+
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+
+	if codec, ok := codecs[mediaType]; ok {
+		return codec, nil
+	}
+	switch {
+	case strings.HasSuffix(mediaType, "+json"):
+		return codecs["application/json"], nil
+	case strings.HasSuffix(mediaType, "+xml"):
+		return codecs["application/xml"], nil
+	}
+	return nil, fmt.Errorf("no codec registered for content type %q", contentType)
+}
+
+// SetBodyCodec sets the request body to v marshaled by the codec registered for contentType
+// and sets the Content-Type header, see RegisterCodec
+func (r *Req) SetBodyCodec(contentType string, v interface{}) *Req {
+	codec, err := lookupCodec(contentType)
+	if err != nil {
+		r.log().Errorf("Can't marshal body Error: %v", err)
+		r.setErr("SetBodyCodec", err)
+		return r
+	}
+
+	data, err := codec.Marshal(v)
+	if err != nil {
+		r.log().Errorf("Can't marshal %s body Error: %v", contentType, err)
+		r.setErr("SetBodyCodec", err)
+		return r
+	}
+
+	r.SetContentType(contentType)
+	return r.SetBody(data)
+}
+
+// Decode unmarshals the response body into v with the codec registered for its Content-Type,
+// see RegisterCodec. The default JSON and XML codecs decode like DecodeJSON and DecodeXML.
+func (r *Response) Decode(v interface{}) error {
+	contentType := r.Headers().Get("Content-Type")
+	codec, err := lookupCodec(contentType)
+	if err != nil {
+		r.log().Errorf("Can't decode response Error: %v", err)
+		return err
+	}
+
+	// Apply the decoder options and charsets
+	switch codec.(type) {
+	case jsonCodec:
+		return r.DecodeJSON(v)
+	case xmlCodec:
+		return r.DecodeXML(v)
+	}
+
+	body, err := r.readBody()
+	if err != nil {
+		return err
+	}
+
+	if err = codec.Unmarshal(body, v); err != nil {
+		r.log().Errorf("Can't decode %s response Error: %v", contentType, err)
+		return err
+	}
+	return nil
+}
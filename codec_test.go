@@ -0,0 +1,103 @@
+package httpreq
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// kvCodec encodes map[string]string as key=value lines
+type kvCodec struct{}
+
+func (kvCodec) Marshal(v interface{}) ([]byte, error) {
+	m, ok := v.(map[string]string)
+	if !ok {
+		return nil, fmt.Errorf("unsupported type %T", v)
+	}
+
+	lines := make([]string, 0, len(m))
+	for k, v := range m {
+		lines = append(lines, k+"="+v)
+	}
+	sort.Strings(lines)
+	return []byte(strings.Join(lines, "\n")), nil
+}
+
+func (kvCodec) Unmarshal(data []byte, v interface{}) error {
+	m, ok := v.(*map[string]string)
+	if !ok {
+		return fmt.Errorf("unsupported type %T", v)
+	}
+
+	*m = make(map[string]string)
+	for _, line := range strings.Split(string(data), "\n") {
+		kv := strings.SplitN(line, "=", 2)
+		if len(kv) != 2 {
+			return fmt.Errorf("invalid line %q", line)
+		}
+		(*m)[kv[0]] = kv[1]
+	}
+	return nil
+}
+
+func TestCodec(t *testing.T) {
+	require.NoError(t, RegisterCodec("application/x-kv; charset=utf-8", kvCodec{}))
+	require.Error(t, RegisterCodec("invalid/", kvCodec{}))
+
+	// Start a local HTTP server which echoes the body with its content type
+	server := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			body, err := ioutil.ReadAll(req.Body)
+			require.NoError(t, err)
+
+			rw.Header().Set("Content-Type", req.Header.Get("Content-Type"))
+			_, _ = rw.Write(body)
+		}),
+	)
+	defer server.Close()
+
+	expected := map[string]string{"a": "1", "b": "2"}
+
+	resp, err := New(context.Background(), server.URL).SetBodyCodec("application/x-kv", expected).Post()
+	require.NoError(t, err)
+	require.Equal(t, "application/x-kv", resp.Headers().Get("Content-Type"))
+
+	var m map[string]string
+	require.NoError(t, resp.Decode(&m))
+	require.Equal(t, expected, m)
+
+	// Default codecs
+	for _, contentType := range []string{"application/json", "application/problem+json", "application/cbor"} {
+		resp, err = New(context.Background(), server.URL).SetBodyCodec(contentType, expected).Post()
+		require.NoError(t, err)
+
+		m = nil
+		require.NoError(t, resp.Decode(&m), contentType)
+		require.Equal(t, expected, m)
+	}
+
+	type Item struct {
+		Name string `xml:"name"`
+	}
+	resp, err = New(context.Background(), server.URL).SetBodyCodec("application/xml", Item{Name: "item"}).Post()
+	require.NoError(t, err)
+
+	var item Item
+	require.NoError(t, resp.Decode(&item))
+	require.Equal(t, "item", item.Name)
+
+	// Errors
+	require.Error(t, New(context.Background(), server.URL).SetBodyCodec("application/unknown", expected).err)
+	require.Error(t, New(context.Background(), server.URL).SetBodyCodec("application/x-kv", 1).err)
+
+	resp, err = New(context.Background(), server.URL).SetContentType("application/unknown").SetBody([]byte("body")).Post()
+	require.NoError(t, err)
+	require.Error(t, resp.Decode(&m))
+}
@@ -0,0 +1,133 @@
+package httpreq
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// decodeContent decodes data encoded with the codings of a Content-Encoding header value,
+// codings are listed in the order they are applied so they are decoded in reverse
+func decodeContent(data []byte, contentEncoding string) ([]byte, error) {
+	codings := strings.Split(contentEncoding, ",")
+
+	for i := len(codings) - 1; i >= 0; i-- {
+		var (
+			reader io.ReadCloser
+			err    error
+		)
+
+		switch coding := strings.ToLower(strings.TrimSpace(codings[i])); coding {
+		case "", "identity":
+			continue
+		case "gzip", "x-gzip":
+			reader, err = gzip.NewReader(bytes.NewReader(data))
+		case "deflate":
+			reader, err = zlib.NewReader(bytes.NewReader(data))
+		default:
+			return nil, fmt.Errorf("unsupported content encoding: %s", coding)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		data, err = ioutil.ReadAll(reader)
+		_ = reader.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return data, nil
+}
+
+// gzipFile streams the file at path compressed with gzip
+func gzipFile(path string) (io.ReadCloser, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		defer f.Close()
+
+		zw := gzip.NewWriter(pw)
+		_, err := io.Copy(zw, f)
+		if err == nil {
+			err = zw.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+
+	return pr, nil
+}
+
+// gzipRequestBody replaces the body of req with its gzip compressed copy
+func gzipRequestBody(req *http.Request) error {
+	var b bytes.Buffer
+	zw := gzip.NewWriter(&b)
+
+	_, err := io.Copy(zw, req.Body)
+	_ = req.Body.Close()
+	if err != nil {
+		return err
+	}
+	if err = zw.Close(); err != nil {
+		return err
+	}
+
+	data := b.Bytes()
+	req.Body = ioutil.NopCloser(bytes.NewReader(data))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(data)), nil
+	}
+	req.ContentLength = int64(len(data))
+	req.Header.Set("Content-Encoding", "gzip")
+	return nil
+}
+
+// gzipReadCloser decodes a gzip body and closes the body with the decoder
+type gzipReadCloser struct {
+	*gzip.Reader
+	body io.ReadCloser
+}
+
+func (g *gzipReadCloser) Close() error {
+	_ = g.Reader.Close()
+	return g.body.Close()
+}
+
+// gunzipBody makes the body of the response decode gzip as it's read, it reports whether
+// the body was gzip encoded. The body is decoded in memory if it's already read.
+func (r *Response) gunzipBody() (bool, error) {
+	if r.resp == nil || !strings.EqualFold(strings.TrimSpace(r.resp.Header.Get("Content-Encoding")), "gzip") {
+		return false, nil
+	}
+
+	if r.bodyRead {
+		data, err := decodeContent(r.data, "gzip")
+		if err != nil {
+			return false, err
+		}
+		r.data = data
+	} else {
+		zr, err := gzip.NewReader(r.resp.Body)
+		if err != nil {
+			return false, err
+		}
+		r.resp.Body = &gzipReadCloser{Reader: zr, body: r.resp.Body}
+	}
+
+	r.resp.Header.Del("Content-Encoding")
+	r.resp.Header.Del("Content-Length")
+	r.resp.ContentLength = -1
+	r.resp.Uncompressed = true
+	return true, nil
+}
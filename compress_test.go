@@ -0,0 +1,229 @@
+package httpreq
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// gzipBytes compresses data with gzip
+func gzipBytes(t *testing.T, data []byte) []byte {
+	t.Helper()
+
+	var b bytes.Buffer
+	w := gzip.NewWriter(&b)
+	_, err := w.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	return b.Bytes()
+}
+
+func TestDisableAutoDecompress(t *testing.T) {
+	compressed := gzipBytes(t, []byte(responseData))
+
+	// Start a local HTTP server which always sends gzip
+	server := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			rw.Header().Set("Content-Encoding", "gzip")
+			_, err := rw.Write(compressed)
+			require.NoError(t, err)
+		}),
+	)
+	defer server.Close()
+
+	// Transparently decompressed by default
+	resp, err := New(context.Background(), server.URL).Get()
+	require.NoError(t, err)
+	body, err := resp.Body()
+	require.NoError(t, err)
+	require.Equal(t, responseData, string(body))
+
+	// Raw bytes when disabled
+	resp, err = New(context.Background(), server.URL).DisableAutoDecompress().Get()
+	require.NoError(t, err)
+	body, err = resp.Body()
+	require.NoError(t, err)
+	require.Equal(t, compressed, body)
+
+	body, err = resp.DecompressedBody()
+	require.NoError(t, err)
+	require.Equal(t, responseData, string(body))
+}
+
+func TestDecodeContent(t *testing.T) {
+	var deflated bytes.Buffer
+	w := zlib.NewWriter(&deflated)
+	_, err := w.Write(gzipBytes(t, []byte(responseData)))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	// gzip is applied first, then deflate
+	data, err := decodeContent(deflated.Bytes(), "gzip, deflate")
+	require.NoError(t, err)
+	require.Equal(t, responseData, string(data))
+
+	data, err = decodeContent([]byte(responseData), "")
+	require.NoError(t, err)
+	require.Equal(t, responseData, string(data))
+
+	_, err = decodeContent([]byte(responseData), "br")
+	require.Error(t, err)
+
+	_, err = decodeContent([]byte(responseData), "gzip")
+	require.Error(t, err)
+}
+
+func TestDecompressedBodyError(t *testing.T) {
+	resp := &Response{resp: &http.Response{
+		Header: http.Header{"Content-Encoding": []string{"gzip"}},
+		Body:   ioutil.NopCloser(bytes.NewBufferString("not gzip")),
+	}}
+	_, err := resp.DecompressedBody()
+	require.Error(t, err)
+}
+
+func TestSetBodyGzipFile(t *testing.T) {
+	content := bytes.Repeat([]byte("2021-01-01 INFO log line\n"), 1000)
+	filePath := filepath.Join(t.TempDir(), "app.log")
+	require.NoError(t, ioutil.WriteFile(filePath, content, 0600))
+
+	// Start a local HTTP server which redirects once so the body is sent again
+	server := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			require.Equal(t, "gzip", req.Header.Get("Content-Encoding"))
+
+			zr, err := gzip.NewReader(req.Body)
+			require.NoError(t, err)
+			body, err := ioutil.ReadAll(zr)
+			require.NoError(t, err)
+			require.Equal(t, content, body)
+
+			if req.URL.Path == "/start" {
+				http.Redirect(rw, req, "/final", http.StatusTemporaryRedirect)
+			}
+		}),
+	)
+	defer server.Close()
+
+	resp, err := New(context.Background(), server.URL+"/start").SetBodyGzipFile(filePath).Post()
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+	require.Equal(t, "/final", resp.Response().Request.URL.Path)
+
+	// Missing file
+	_, err = New(context.Background(), server.URL).SetBodyGzipFile(filepath.Join(t.TempDir(), "missing")).Post()
+	require.Error(t, err)
+}
+
+func TestSetAutoCompress(t *testing.T) {
+
+	// Start a local HTTP server which returns the decoded body
+	server := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			body, err := ioutil.ReadAll(req.Body)
+			require.NoError(t, err)
+			require.Equal(t, int64(len(body)), req.ContentLength)
+
+			if req.Header.Get("Content-Encoding") == "gzip" {
+				zr, err := gzip.NewReader(bytes.NewReader(body))
+				require.NoError(t, err)
+				body, err = ioutil.ReadAll(zr)
+				require.NoError(t, err)
+				rw.Header().Set("X-Compressed", "true")
+			}
+			_, err = rw.Write(body)
+			require.NoError(t, err)
+		}),
+	)
+	defer server.Close()
+
+	small := []byte("small body")
+	large := bytes.Repeat([]byte("large body "), 100)
+
+	// Below the threshold
+	resp, err := New(context.Background(), server.URL).SetAutoCompress(100).SetBody(small).Post()
+	require.NoError(t, err)
+	require.Empty(t, resp.Headers().Get("X-Compressed"))
+	body, err := resp.Body()
+	require.NoError(t, err)
+	require.Equal(t, small, body)
+
+	// Above the threshold
+	resp, err = New(context.Background(), server.URL).SetAutoCompress(100).SetBody(large).Post()
+	require.NoError(t, err)
+	require.Equal(t, "true", resp.Headers().Get("X-Compressed"))
+	body, err = resp.Body()
+	require.NoError(t, err)
+	require.Equal(t, large, body)
+}
+
+func TestSetAcceptEncoding(t *testing.T) {
+
+	// Start a local HTTP server compressing with the requested encoding
+	server := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			switch req.Header.Get("Accept-Encoding") {
+			case "gzip":
+				rw.Header().Set("Content-Encoding", "gzip")
+				_, _ = rw.Write(gzipBytes(t, []byte(responseData)))
+			case "deflate, gzip":
+				var b bytes.Buffer
+				zw := zlib.NewWriter(&b)
+				_, _ = zw.Write([]byte(responseData))
+				_ = zw.Close()
+				rw.Header().Set("Content-Encoding", "deflate")
+				_, _ = rw.Write(b.Bytes())
+			default:
+				_, _ = rw.Write([]byte(responseData))
+			}
+		}),
+	)
+	defer server.Close()
+
+	resp, err := New(context.Background(), server.URL).SetAcceptEncoding("gzip").Get()
+	require.NoError(t, err)
+	require.Equal(t, "gzip", resp.Headers().Get("Content-Encoding"))
+
+	body, err := resp.Body()
+	require.NoError(t, err)
+	require.Equal(t, responseData, string(body))
+	require.Empty(t, resp.Headers().Get("Content-Encoding"))
+
+	// Decoding again is a no-op
+	body, err = resp.DecompressedBody()
+	require.NoError(t, err)
+	require.Equal(t, responseData, string(body))
+
+	resp, err = New(context.Background(), server.URL).SetAcceptEncoding("deflate", "gzip").Get()
+	require.NoError(t, err)
+	body, err = resp.Body()
+	require.NoError(t, err)
+	require.Equal(t, responseData, string(body))
+
+	// Files are saved decoded when the body is streamed to them too
+	filePath := filepath.Join(t.TempDir(), "file")
+	resp, err = New(context.Background(), server.URL).SetAcceptEncoding("gzip").SetMaxResponseSize(1 << 20).Get()
+	require.NoError(t, err)
+	require.NoError(t, resp.SaveFile(filePath))
+
+	data, err := ioutil.ReadFile(filePath)
+	require.NoError(t, err)
+	require.Equal(t, responseData, string(data))
+
+	// Setting the header directly returns the compressed bytes
+	resp, err = New(context.Background(), server.URL).SetHeaders(map[string]string{"Accept-Encoding": "gzip"}).DisableAutoDecompress().Get()
+	require.NoError(t, err)
+	body, err = resp.Body()
+	require.NoError(t, err)
+	require.Equal(t, gzipBytes(t, []byte(responseData)), body)
+
+	require.Error(t, New(context.Background(), server.URL).SetAcceptEncoding("br").err)
+}
@@ -0,0 +1,53 @@
+package httpreq
+
+import (
+	"net"
+	"sync/atomic"
+	"time"
+)
+
+// byteCounter holds the number of bytes read and written on connections
+type byteCounter struct {
+	read    int64
+	written int64
+}
+
+func (c *byteCounter) Read() int64 {
+	return atomic.LoadInt64(&c.read)
+}
+
+func (c *byteCounter) Written() int64 {
+	return atomic.LoadInt64(&c.written)
+}
+
+// countingConn counts the bytes read and written on the wrapped net.Conn
+type countingConn struct {
+	net.Conn
+	counter *byteCounter
+}
+
+func (c *countingConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	atomic.AddInt64(&c.counter.read, int64(n))
+	return n, err
+}
+
+func (c *countingConn) Write(p []byte) (int, error) {
+	n, err := c.Conn.Write(p)
+	atomic.AddInt64(&c.counter.written, int64(n))
+	return n, err
+}
+
+// deadlineConn extends the read deadline of the wrapped net.Conn before every read,
+// so a read fails when no data arrives for the timeout
+type deadlineConn struct {
+	net.Conn
+	timeout time.Duration
+}
+
+func (c *deadlineConn) Read(p []byte) (int, error) {
+	if err := c.Conn.SetReadDeadline(time.Now().Add(c.timeout)); err != nil {
+		return 0, err
+	}
+	return c.Conn.Read(p)
+}
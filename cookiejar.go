@@ -0,0 +1,131 @@
+package httpreq
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+)
+
+// SetPersistentCookieJar sets a cookie jar saved as JSON to path after every response setting
+// cookies, so sessions survive process restarts. Cookies in the file are loaded when it's called.
+// The file contains session secrets, it's created readable only by the owner.
+func (r *Req) SetPersistentCookieJar(path string) *Req {
+	jar, err := newPersistentJar(path, r.log())
+	if err != nil {
+		r.log().Errorf("Can't load cookie jar %s Error: %v", path, err)
+		r.setErr("SetPersistentCookieJar", err)
+		return r
+	}
+
+	r.client.Jar = jar
+	return r
+}
+
+// storedCookie is a cookie in the persistent jar file with the URL which set it
+type storedCookie struct {
+	URL    string       `json:"url"`
+	Cookie *http.Cookie `json:"cookie"`
+}
+
+// persistentJar is a cookie jar saving its cookies to a file
+type persistentJar struct {
+	jar  *cookiejar.Jar
+	path string
+	log  Logger
+
+	mu      sync.Mutex
+	cookies map[string]storedCookie
+}
+
+// newPersistentJar creates a jar with the cookies saved in path, if it exists
+func newPersistentJar(path string, log Logger) (*persistentJar, error) {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	j := &persistentJar{jar: jar, path: path, log: log, cookies: make(map[string]storedCookie)}
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return j, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var stored []storedCookie
+	if err = json.Unmarshal(data, &stored); err != nil {
+		return nil, err
+	}
+
+	for _, s := range stored {
+		u, err := url.Parse(s.URL)
+		if err != nil {
+			return nil, err
+		}
+		j.set(u, s.Cookie)
+		j.jar.SetCookies(u, []*http.Cookie{s.Cookie})
+	}
+	return j, nil
+}
+
+// SetCookies stores the cookies of a response and saves the jar
+func (j *persistentJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	j.jar.SetCookies(u, cookies)
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	for _, c := range cookies {
+		j.set(u, c)
+	}
+
+	if err := j.save(); err != nil {
+		j.log.Errorf("Can't save cookie jar %s Error: %v", j.path, err)
+	}
+}
+
+// Cookies returns the cookies to send to u
+func (j *persistentJar) Cookies(u *url.URL) []*http.Cookie {
+	return j.jar.Cookies(u)
+}
+
+// set records a cookie set by u, deleted and expired cookies are removed
+func (j *persistentJar) set(u *url.URL, c *http.Cookie) {
+	key := u.Hostname() + ";" + c.Domain + ";" + c.Path + ";" + c.Name
+	if c.MaxAge < 0 || !c.Expires.IsZero() && c.Expires.Before(time.Now()) {
+		delete(j.cookies, key)
+		return
+	}
+
+	// Max-Age is relative to now, keep the expiry time instead
+	if c.MaxAge > 0 {
+		expiring := *c
+		expiring.Expires = time.Now().Add(time.Duration(c.MaxAge) * time.Second)
+		expiring.MaxAge = 0
+		c = &expiring
+	}
+
+	// The path of the URL is the default path of the cookie
+	j.cookies[key] = storedCookie{URL: (&url.URL{Scheme: u.Scheme, Host: u.Host, Path: u.Path}).String(), Cookie: c}
+}
+
+// save writes the cookies to the file
+func (j *persistentJar) save() error {
+	stored := make([]storedCookie, 0, len(j.cookies))
+	for _, s := range j.cookies {
+		stored = append(stored, s)
+	}
+
+	data, err := json.Marshal(stored)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(j.path, data, 0600)
+}
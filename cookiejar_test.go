@@ -0,0 +1,60 @@
+package httpreq
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetPersistentCookieJar(t *testing.T) {
+
+	// Start a local HTTP server with a login session
+	server := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			switch req.URL.Path {
+			case "/login":
+				http.SetCookie(rw, &http.Cookie{Name: "session", Value: "abc", Path: "/", MaxAge: 3600})
+				http.SetCookie(rw, &http.Cookie{Name: "deleted", Value: "x", Path: "/", MaxAge: -1})
+			case "/me":
+				cookie, err := req.Cookie("session")
+				if err != nil || cookie.Value != "abc" {
+					rw.WriteHeader(http.StatusUnauthorized)
+				}
+			}
+		}),
+	)
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "cookies.json")
+
+	r := New(context.Background(), server.URL+"/login").SetPersistentCookieJar(path)
+	require.NoError(t, r.err)
+	_, err := r.Get()
+	require.NoError(t, err)
+
+	data, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	require.Contains(t, string(data), `"abc"`)
+	require.NotContains(t, string(data), "deleted")
+
+	// A new request, i.e. after a restart, loads the session
+	r = New(context.Background(), server.URL+"/me").SetPersistentCookieJar(path)
+	require.NoError(t, r.err)
+	resp, err := r.Get()
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+
+	// Without the jar
+	resp, err = New(context.Background(), server.URL+"/me").Get()
+	require.NoError(t, err)
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode())
+
+	// Invalid files fail the chain
+	require.NoError(t, ioutil.WriteFile(path, []byte("invalid"), 0600))
+	require.Error(t, New(context.Background(), server.URL).SetPersistentCookieJar(path).err)
+}
@@ -0,0 +1,79 @@
+package httpreq
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// curlBodyPlaceholder stands for bodies which are known only when the request is sent
+const curlBodyPlaceholder = "<body>"
+
+// ToCurl returns the request as an equivalent curl command line for debugging.
+// Secrets like Authorization credentials are included, use ToCurlRedacted to share it.
+// Nothing is sent or consumed: bodies known only when sending (SetBodyFunc, SetBodyFromRequest...)
+// are shown as a placeholder, and the Idempotency-Key and OAuth2 token aren't included.
+func (r *Req) ToCurl() (string, error) {
+	return r.toCurl(func(s string) string { return s })
+}
+
+// ToCurlRedacted returns the curl command line of ToCurl with secrets redacted like in logs,
+// including the redactor set by SetRedactor
+func (r *Req) ToCurlRedacted() (string, error) {
+	return r.toCurl(func(s string) string {
+		s = redactSecrets(s)
+		if r.redactor != nil {
+			s = r.redactor(s)
+		}
+		return s
+	})
+}
+
+// toCurl renders the request as a curl command line, applying redact to each argument
+func (r *Req) toCurl(redact func(string) string) (string, error) {
+	// If there is an error in chain, then do nothing and return error
+	if r.err != nil {
+		return "", r.err
+	}
+
+	req, err := r.baseRequest(r.request.Method)
+	if err != nil {
+		return "", err
+	}
+
+	// curl switches to POST with --data-binary, so GET is explicit when there is a body,
+	// and -X HEAD would wait for a body which isn't sent
+	hasBody := r.body != nil || r.hasBody()
+	args := []string{"curl"}
+	switch {
+	case req.Method == http.MethodHead:
+		args = append(args, "-I")
+	case req.Method != http.MethodGet || hasBody:
+		args = append(args, "-X", shellQuote(req.Method))
+	}
+	args = append(args, shellQuote(redact(req.URL.String())))
+
+	names := make([]string, 0, len(req.Header))
+	for name := range req.Header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, value := range req.Header[name] {
+			args = append(args, "-H", shellQuote(redact(name+": "+value)))
+		}
+	}
+
+	if r.body != nil {
+		args = append(args, "--data-binary", shellQuote(redact(string(r.body))))
+	} else if hasBody {
+		args = append(args, "--data-binary", shellQuote(curlBodyPlaceholder))
+	}
+
+	return strings.Join(args, " "), nil
+}
+
+// shellQuote quotes s as a single argument for POSIX shells
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,76 @@
+package httpreq
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestToCurl(t *testing.T) {
+	r := New(context.Background(), "http://example.com/path?q=it's").
+		SetMethod(http.MethodPost).
+		SetHeaders(map[string]string{"Authorization": "Bearer secret", "X-Request-Id": "id"}).
+		SetBodyJSON(map[string]string{"name": "value"})
+
+	curl, err := r.ToCurl()
+	require.NoError(t, err)
+	require.Equal(t, `curl -X 'POST' 'http://example.com/path?q=it'\''s' -H 'Authorization: Bearer secret' `+
+		`-H 'Content-Type: application/json' -H 'X-Request-Id: id' --data-binary '{"name":"value"}'`, curl)
+
+	redactedCurl, err := r.ToCurlRedacted()
+	require.NoError(t, err)
+	require.Contains(t, redactedCurl, "-H 'Authorization: Bearer REDACTED'")
+	require.NotContains(t, redactedCurl, "secret")
+
+	// The body can still be sent
+	req, err := r.Request()
+	require.NoError(t, err)
+	require.Equal(t, int64(16), req.ContentLength)
+
+	curl, err = New(context.Background(), "http://example.com").ToCurl()
+	require.NoError(t, err)
+	require.Equal(t, "curl 'http://example.com'", curl)
+
+	// GET with a body stays a GET
+	curl, err = New(context.Background(), "http://example.com").SetBody([]byte("q")).ToCurl()
+	require.NoError(t, err)
+	require.Equal(t, "curl -X 'GET' 'http://example.com' --data-binary 'q'", curl)
+
+	curl, err = New(context.Background(), "http://example.com").SetMethod(http.MethodHead).ToCurl()
+	require.NoError(t, err)
+	require.Equal(t, "curl -I 'http://example.com'", curl)
+}
+
+func TestToCurlNoSideEffects(t *testing.T) {
+	var received string
+
+	// Start a local HTTP server
+	server := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			body, _ := ioutil.ReadAll(req.Body)
+			received = string(body)
+		}),
+	)
+	defer server.Close()
+
+	in := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("incoming"))
+	ts := &countingTokenSource{}
+	r := New(context.Background(), server.URL).SetTokenSource(ts).AutoIdempotencyKey().SetBodyFromRequest(in)
+
+	curl, err := r.ToCurl()
+	require.NoError(t, err)
+	require.Contains(t, curl, "--data-binary '<body>'")
+	require.NotContains(t, curl, "Authorization")
+	require.NotContains(t, curl, "Idempotency-Key")
+	require.Zero(t, ts.calls)
+
+	// The one-shot body is still sent
+	_, err = r.Post()
+	require.NoError(t, err)
+	require.Equal(t, "incoming", received)
+}
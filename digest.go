@@ -0,0 +1,225 @@
+package httpreq
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// digestCredentials are the credentials set by SetDigestAuth
+type digestCredentials struct {
+	username string
+	password string
+}
+
+// digestChallenge holds the parameters of a "WWW-Authenticate: Digest" challenge
+type digestChallenge struct {
+	realm     string
+	nonce     string
+	opaque    string
+	algorithm string
+	qop       string
+}
+
+// SetDigestAuth enables HTTP Digest authentication. When the server responds 401 with a Digest
+// challenge, the request is sent again with the computed Authorization header.
+// Only the "auth" quality of protection with MD5 and SHA-256 (and their -sess variants) is supported.
+func (r *Req) SetDigestAuth(username, password string) *Req {
+	r.digestAuth = &digestCredentials{username: username, password: password}
+	return r
+}
+
+// digestRetry answers the Digest challenge of a 401 response by sending req again with authorization
+func (r *Req) digestRetry(req *http.Request, resp *http.Response) (*http.Response, error) {
+	if resp.StatusCode != http.StatusUnauthorized {
+		return resp, nil
+	}
+
+	challenge, ok := findDigestChallenge(resp.Header.Values("WWW-Authenticate"))
+	if !ok {
+		return resp, nil
+	}
+
+	// The body must be sent again
+	hasBody := req.Body != nil && req.Body != http.NoBody
+	if hasBody && req.GetBody == nil {
+		r.log().Warnf("Can't answer digest challenge, request body can't be sent again")
+		return resp, nil
+	}
+
+	authorization, err := challenge.authorize(r.digestAuth, req.Method, req.URL.RequestURI())
+	if err != nil {
+		r.log().Errorf("Can't answer digest challenge Error: %v", err)
+		return resp, nil
+	}
+
+	drainBody(resp.Body)
+
+	retry := req.Clone(req.Context())
+	if hasBody {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		retry.Body = body
+	}
+	retry.Header.Set("Authorization", authorization)
+
+	return r.client.Do(retry)
+}
+
+// findDigestChallenge returns the first supported Digest challenge of WWW-Authenticate header values
+func findDigestChallenge(values []string) (*digestChallenge, bool) {
+	for _, value := range values {
+		scheme, rest := value, ""
+		if i := strings.IndexByte(value, ' '); i >= 0 {
+			scheme, rest = value[:i], value[i+1:]
+		}
+		if !strings.EqualFold(scheme, "Digest") {
+			continue
+		}
+
+		params := parseAuthParams(rest)
+		c := &digestChallenge{
+			realm:     params["realm"],
+			nonce:     params["nonce"],
+			opaque:    params["opaque"],
+			algorithm: params["algorithm"],
+		}
+
+		// Only "auth" is supported, "auth-int" requires hashing the body
+		if qop, ok := params["qop"]; ok {
+			for _, q := range strings.Split(qop, ",") {
+				if strings.TrimSpace(q) == "auth" {
+					c.qop = "auth"
+				}
+			}
+			if c.qop == "" {
+				continue
+			}
+		}
+
+		if c.nonce == "" || c.hash() == nil {
+			continue
+		}
+
+		return c, true
+	}
+	return nil, false
+}
+
+// hash returns the hash function of the challenge algorithm, nil if it's not supported
+func (c *digestChallenge) hash() func() hash.Hash {
+	switch strings.TrimSuffix(strings.ToUpper(c.algorithm), "-SESS") {
+	case "", "MD5":
+		return md5.New
+	case "SHA-256":
+		return sha256.New
+	}
+	return nil
+}
+
+// authorize returns the Authorization header value answering the challenge
+func (c *digestChallenge) authorize(creds *digestCredentials, method, uri string) (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return c.authorizeWithCnonce(creds, method, uri, hex.EncodeToString(b)), nil
+}
+
+// authorizeWithCnonce returns the Authorization header value for the given client nonce
+func (c *digestChallenge) authorizeWithCnonce(creds *digestCredentials, method, uri, cnonce string) string {
+	newHash := c.hash()
+	h := func(s string) string {
+		hh := newHash()
+		_, _ = io.WriteString(hh, s)
+		return hex.EncodeToString(hh.Sum(nil))
+	}
+
+	const nc = "00000001"
+
+	ha1 := h(creds.username + ":" + c.realm + ":" + creds.password)
+	if strings.HasSuffix(strings.ToUpper(c.algorithm), "-SESS") {
+		ha1 = h(ha1 + ":" + c.nonce + ":" + cnonce)
+	}
+	ha2 := h(method + ":" + uri)
+
+	var response string
+	if c.qop == "" {
+		response = h(ha1 + ":" + c.nonce + ":" + ha2)
+	} else {
+		response = h(ha1 + ":" + c.nonce + ":" + nc + ":" + cnonce + ":" + c.qop + ":" + ha2)
+	}
+
+	params := []string{
+		fmt.Sprintf("username=%q", creds.username),
+		fmt.Sprintf("realm=%q", c.realm),
+		fmt.Sprintf("nonce=%q", c.nonce),
+		fmt.Sprintf("uri=%q", uri),
+		fmt.Sprintf("response=%q", response),
+	}
+	if c.algorithm != "" {
+		params = append(params, "algorithm="+c.algorithm)
+	}
+	if c.opaque != "" {
+		params = append(params, fmt.Sprintf("opaque=%q", c.opaque))
+	}
+	if c.qop != "" {
+		params = append(params, "qop="+c.qop, "nc="+nc, fmt.Sprintf("cnonce=%q", cnonce))
+	}
+
+	return "Digest " + strings.Join(params, ", ")
+}
+
+// parseAuthParams parses comma separated key=value pairs of an authentication header,
+// values may be quoted strings containing commas
+func parseAuthParams(s string) map[string]string {
+	params := make(map[string]string)
+
+	for {
+		s = strings.TrimLeft(s, " \t,")
+		if s == "" {
+			return params
+		}
+
+		i := strings.IndexByte(s, '=')
+		if i < 0 {
+			return params
+		}
+		key := strings.ToLower(strings.TrimSpace(s[:i]))
+		s = strings.TrimLeft(s[i+1:], " \t")
+
+		var value string
+		if strings.HasPrefix(s, `"`) {
+			var b strings.Builder
+			j := 1
+			for ; j < len(s) && s[j] != '"'; j++ {
+				if s[j] == '\\' && j+1 < len(s) {
+					j++
+				}
+				b.WriteByte(s[j])
+			}
+			value = b.String()
+			if j < len(s) {
+				j++
+			}
+			s = s[j:]
+		} else {
+			j := strings.IndexByte(s, ',')
+			if j < 0 {
+				j = len(s)
+			}
+			value = strings.TrimSpace(s[:j])
+			s = s[j:]
+		}
+
+		params[key] = value
+	}
+}
@@ -0,0 +1,85 @@
+package httpreq
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetDigestAuth(t *testing.T) {
+	const realm, nonce, opaque = "test@example.com", "7ypf/xlj9XXwfDPEoM4URrv/xwf94BcCAzFZH4GiTo0v", "FQhe/qaU925kfnzjCev0ciny7QMkPqMAFRtzCUYo5tdS"
+
+	sha := func(s string) string {
+		h := sha256.Sum256([]byte(s))
+		return hex.EncodeToString(h[:])
+	}
+
+	// Start a local HTTP server which requires digest authentication
+	server := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			body, err := ioutil.ReadAll(req.Body)
+			require.NoError(t, err)
+			require.Equal(t, "body", string(body))
+
+			auth := req.Header.Get("Authorization")
+			if !strings.HasPrefix(auth, "Digest ") {
+				rw.Header().Add("WWW-Authenticate", `Basic realm="other"`)
+				rw.Header().Add("WWW-Authenticate", `Digest realm="`+realm+`", qop="auth, auth-int", algorithm=SHA-256, nonce="`+nonce+`", opaque="`+opaque+`"`)
+				rw.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			params := parseAuthParams(strings.TrimPrefix(auth, "Digest "))
+			require.Equal(t, "user", params["username"])
+			require.Equal(t, opaque, params["opaque"])
+			require.Equal(t, "SHA-256", params["algorithm"])
+			require.Equal(t, req.URL.RequestURI(), params["uri"])
+
+			ha1 := sha("user:" + realm + ":pass")
+			ha2 := sha(req.Method + ":" + params["uri"])
+			expected := sha(ha1 + ":" + nonce + ":" + params["nc"] + ":" + params["cnonce"] + ":" + params["qop"] + ":" + ha2)
+			if params["response"] != expected {
+				rw.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			_, err = rw.Write([]byte(responseData))
+			require.NoError(t, err)
+		}),
+	)
+	defer server.Close()
+
+	resp, err := New(context.Background(), server.URL+"/dir/index.html?a=1").SetDigestAuth("user", "pass").SetBody([]byte("body")).Post()
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+
+	body, err := resp.Body()
+	require.NoError(t, err)
+	require.Equal(t, responseData, string(body))
+
+	// Wrong password
+	resp, err = New(context.Background(), server.URL).SetDigestAuth("user", "wrong").SetBody([]byte("body")).Post()
+	require.NoError(t, err)
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode())
+}
+
+func TestDigestAuthorization(t *testing.T) {
+	// Example from RFC 2617
+	challenge, ok := findDigestChallenge([]string{`Digest realm="testrealm@host.com", qop="auth,auth-int", nonce="dcd98b7102dd2f0e8b11d0f600bfb0c093", opaque="5ccc069c403ebaf9f0171e9517f40e41"`})
+	require.True(t, ok)
+
+	authorization := challenge.authorizeWithCnonce(&digestCredentials{username: "Mufasa", password: "Circle Of Life"}, http.MethodGet, "/dir/index.html", "0a4f113b")
+	require.Contains(t, authorization, `response="6629fae49393a05397450978507c4ef1"`)
+	require.Contains(t, authorization, `opaque="5ccc069c403ebaf9f0171e9517f40e41"`)
+	require.Contains(t, authorization, "qop=auth, nc=00000001")
+
+	// Unsupported challenges
+	_, ok = findDigestChallenge([]string{`Basic realm="x"`, `Digest realm="x", nonce="n", algorithm=SHA-512-256`, `Digest realm="x", nonce="n", qop="auth-int"`})
+	require.False(t, ok)
+}
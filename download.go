@@ -0,0 +1,137 @@
+package httpreq
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// DownloadResult is the result of downloading one URL with DownloadAll
+type DownloadResult struct {
+	// URL is the downloaded URL
+	URL string
+
+	// Path is the path of the saved file, empty on failure
+	Path string
+
+	// Size is the size of the saved file in bytes
+	Size int64
+
+	// Err is the error if the download failed
+	Err error
+}
+
+// DownloadAll downloads urls into dir like DownloadFile, with at most concurrency downloads
+// at a time. Results are in the order of urls. Downloads with the same file name are saved
+// with a suffix, i.e. app-1.bin, so none is lost. It returns an error if dir isn't a directory
+// or any download failed, the error of each download is in its result.
+func DownloadAll(ctx context.Context, urls []string, dir string, concurrency int) ([]DownloadResult, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", dir)
+	}
+
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	results := make([]DownloadResult, len(urls))
+	names := &fileNames{names: make(map[string]bool)}
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+
+	for i, url := range urls {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, url string) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			results[i] = download(ctx, url, dir, names)
+		}(i, url)
+	}
+	wg.Wait()
+
+	failed := 0
+	for _, result := range results {
+		if result.Err != nil {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return results, fmt.Errorf("%d of %d downloads failed", failed, len(urls))
+	}
+	return results, nil
+}
+
+// fileNames reserves unique file names for the downloads of a DownloadAll call
+type fileNames struct {
+	mu    sync.Mutex
+	names map[string]bool
+}
+
+// reserve returns name, or name with a numeric suffix if it's already reserved
+func (f *fileNames) reserve(name string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	unique := name
+	for i := 1; f.names[unique]; i++ {
+		unique = fmt.Sprintf("%s-%d%s", base, i, ext)
+	}
+	f.names[unique] = true
+
+	return unique
+}
+
+// download downloads url into dir with a file name reserved in names
+func download(ctx context.Context, url string, dir string, names *fileNames) DownloadResult {
+	result := DownloadResult{URL: url}
+
+	resp, err := New(ctx, url).FailOnError().Get()
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	defer resp.Close()
+
+	// The file is saved in a directory of its own until its name is reserved
+	tmpDir, err := ioutil.TempDir(dir, ".download")
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	defer os.RemoveAll(tmpDir)
+
+	_, tmpPath, err := resp.DownloadFile(tmpDir)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	filePath := filepath.Join(dir, names.reserve(filepath.Base(tmpPath)))
+	if err = os.Rename(tmpPath, filePath); err != nil {
+		result.Err = err
+		return result
+	}
+
+	info, err := os.Stat(filePath)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	result.Path = filePath
+	result.Size = info.Size()
+	return result
+}
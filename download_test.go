@@ -0,0 +1,99 @@
+package httpreq
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDownloadAll(t *testing.T) {
+
+	// Start a local HTTP server serving files named after the path
+	server := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			if req.URL.Path == "/missing" {
+				rw.WriteHeader(http.StatusNotFound)
+				return
+			}
+			name := req.URL.Path[1:]
+			rw.Header().Set("Content-Disposition", `attachment; filename="`+name+`"`)
+			_, _ = rw.Write([]byte("content of " + name))
+		}),
+	)
+	defer server.Close()
+
+	dir := t.TempDir()
+	names := []string{"a.bin", "b.bin", "c.bin", "d.bin", "e.bin"}
+	urls := make([]string, len(names))
+	for i, name := range names {
+		urls[i] = server.URL + "/" + name
+	}
+
+	results, err := DownloadAll(context.Background(), urls, dir, 2)
+	require.NoError(t, err)
+	require.Len(t, results, len(names))
+
+	for i, result := range results {
+		require.NoError(t, result.Err)
+		require.Equal(t, urls[i], result.URL)
+		require.Equal(t, filepath.Join(dir, names[i]), result.Path)
+
+		data, err := ioutil.ReadFile(result.Path)
+		require.NoError(t, err)
+		require.Equal(t, "content of "+names[i], string(data))
+		require.Equal(t, int64(len(data)), result.Size)
+	}
+
+	// Failed downloads are reported per URL
+	results, err = DownloadAll(context.Background(), []string{urls[0], server.URL + "/missing"}, dir, 0)
+	require.Error(t, err)
+	require.NoError(t, results[0].Err)
+	require.Error(t, results[1].Err)
+	require.Empty(t, results[1].Path)
+
+	_, err = DownloadAll(context.Background(), urls, filepath.Join(dir, "a.bin"), 2)
+	require.Error(t, err)
+}
+
+func TestDownloadAllSameName(t *testing.T) {
+
+	// Start a local HTTP server serving every path as app.bin
+	server := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			rw.Header().Set("Content-Disposition", `attachment; filename="app.bin"`)
+			_, _ = rw.Write([]byte("content of " + req.URL.Path))
+		}),
+	)
+	defer server.Close()
+
+	dir := t.TempDir()
+	urls := []string{server.URL + "/v1/app.bin", server.URL + "/v2/app.bin", server.URL + "/v3/app.bin"}
+
+	results, err := DownloadAll(context.Background(), urls, dir, len(urls))
+	require.NoError(t, err)
+
+	// Every download is kept in a file of its own
+	paths := make(map[string]bool)
+	for _, result := range results {
+		require.NoError(t, result.Err)
+		paths[result.Path] = true
+
+		data, err := ioutil.ReadFile(result.Path)
+		require.NoError(t, err)
+		require.Equal(t, "content of "+result.URL[len(server.URL):], string(data))
+	}
+	require.Equal(t, map[string]bool{
+		filepath.Join(dir, "app.bin"):   true,
+		filepath.Join(dir, "app-1.bin"): true,
+		filepath.Join(dir, "app-2.bin"): true,
+	}, paths)
+
+	files, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, files, len(urls))
+}
@@ -0,0 +1,100 @@
+package httpreq
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+)
+
+// HTTPError is returned with the Response when the status code is 400 or above after Req.FailOnError,
+// or when the function set by Req.SetSuccessFunc reports a failure
+type HTTPError struct {
+	StatusCode int
+	Status     string
+
+	// Response is the failed response, it's also returned by the request for inspection
+	Response *Response
+
+	// body is the buffered body of the response
+	body []byte
+}
+
+// Error returns the status of the response
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("http error: %s", e.Status)
+}
+
+// Body returns the body of the failed response, i.e. the error payload of an API, so it's available
+// where only the error is passed on. It's nil if the body couldn't be read or Req.SetDoNotBuffer is set.
+func (e *HTTPError) Body() []byte {
+	return e.body
+}
+
+// FailOnError makes requests return an *HTTPError with the Response when the status code is 400 or above
+func (r *Req) FailOnError() *Req {
+	r.failOnError = true
+	return r
+}
+
+// Err returns the first error of a setter in the chain, which is returned when the request is sent.
+// It's wrapped with the name of the setter, i.e. "SetForm: open report.pdf: no such file or directory".
+// Errors of later setters don't replace it.
+func (r *Req) Err() error {
+	return r.err
+}
+
+// setErr sets the chain error to err of setter unless an earlier setter already failed
+func (r *Req) setErr(setter string, err error) {
+	if r.err == nil {
+		r.err = fmt.Errorf("%s: %w", setter, err)
+	}
+}
+
+// SetSuccessFunc sets the function deciding whether a response is a success for FailOnError and
+// SetRetry instead of its status code, i.e. for APIs responding 200 with an error field. Unsuccessful
+// responses are retried. The function may read the body, it can be read again afterwards.
+func (r *Req) SetSuccessFunc(f func(*Response) bool) *Req {
+	r.successFunc = f
+	return r
+}
+
+// checkStatus returns an *HTTPError for an error response if enabled by FailOnError
+func (r *Req) checkStatus(resp *Response) error {
+	if !r.failOnError || r.succeeded(resp) {
+		return nil
+	}
+
+	status := resp.resp.Status
+	if status == "" {
+		status = fmt.Sprintf("%d", resp.StatusCode())
+	}
+
+	// Buffer the body for diagnostics, it can still be read from the Response
+	var body []byte
+	if !resp.noBuffer && resp.resp.Body != nil {
+		body, _ = resp.readBody()
+	}
+
+	return &HTTPError{StatusCode: resp.StatusCode(), Status: status, Response: resp, body: body}
+}
+
+// succeeded reports whether the response is a success by the function set by SetSuccessFunc
+// or by its status code
+func (r *Req) succeeded(resp *Response) bool {
+	if r.successFunc != nil {
+		return r.successFunc(resp)
+	}
+	return resp.StatusCode() < 400
+}
+
+// succeededRaw is succeeded for a response before it's wrapped, the body read by the
+// success function is restored for the caller
+func (r *Req) succeededRaw(resp *http.Response) bool {
+	response := &Response{resp: resp, logger: r.log()}
+	success := r.succeeded(response)
+	if response.bodyRead {
+		resp.Body = ioutil.NopCloser(bytes.NewReader(response.data))
+	}
+	return success
+}
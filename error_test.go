@@ -0,0 +1,166 @@
+package httpreq
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFailOnError(t *testing.T) {
+
+	// Start a local HTTP server
+	server := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			if req.URL.Path == "/missing" {
+				http.Error(rw, "not found", http.StatusNotFound)
+				return
+			}
+			_, err := rw.Write([]byte(responseData))
+			require.NoError(t, err)
+		}),
+	)
+	defer server.Close()
+
+	resp, err := New(context.Background(), server.URL+"/missing").FailOnError().Get()
+	require.Error(t, err)
+	require.Equal(t, "http error: 404 Not Found", err.Error())
+
+	var httpErr *HTTPError
+	require.True(t, errors.As(err, &httpErr))
+	require.Equal(t, http.StatusNotFound, httpErr.StatusCode)
+	require.Equal(t, resp, httpErr.Response)
+
+	// The response is returned for inspection
+	require.NotNil(t, resp)
+	body, err := resp.Body()
+	require.NoError(t, err)
+	require.Equal(t, "not found\n", string(body))
+
+	resp, err = New(context.Background(), server.URL).FailOnError().Get()
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+
+	// Disabled by default
+	resp, err = New(context.Background(), server.URL+"/missing").Get()
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNotFound, resp.StatusCode())
+}
+
+func TestHTTPErrorBody(t *testing.T) {
+	payload := `{"error":"invalid_request","message":"name is required"}`
+
+	// Start a local HTTP server responding with an error payload
+	server := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			rw.WriteHeader(http.StatusBadRequest)
+			_, _ = rw.Write([]byte(payload))
+		}),
+	)
+	defer server.Close()
+
+	resp, err := New(context.Background(), server.URL).FailOnError().Post()
+	var httpErr *HTTPError
+	require.True(t, errors.As(err, &httpErr))
+	require.Equal(t, payload, string(httpErr.Body()))
+
+	// The body is still readable from the response
+	body, err := resp.Body()
+	require.NoError(t, err)
+	require.Equal(t, payload, string(body))
+
+	// Not buffered
+	_, err = New(context.Background(), server.URL).FailOnError().SetDoNotBuffer().Post()
+	require.True(t, errors.As(err, &httpErr))
+	require.Nil(t, httpErr.Body())
+}
+
+func TestSetSuccessFunc(t *testing.T) {
+	var requests int32
+
+	// Start a local HTTP server which reports errors in the body
+	server := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			if atomic.AddInt32(&requests, 1) <= 2 {
+				_, _ = rw.Write([]byte(`{"success":false}`))
+				return
+			}
+			_, _ = rw.Write([]byte(`{"success":true}`))
+		}),
+	)
+	defer server.Close()
+
+	success := func(resp *Response) bool {
+		var result struct {
+			Success bool `json:"success"`
+		}
+		return resp.DecodeJSON(&result) == nil && result.Success
+	}
+
+	// 200 with an error field is a failure
+	resp, err := New(context.Background(), server.URL).SetSuccessFunc(success).FailOnError().Get()
+	var httpErr *HTTPError
+	require.True(t, errors.As(err, &httpErr))
+	require.Equal(t, http.StatusOK, httpErr.StatusCode)
+
+	// The body can be read again
+	body, err := resp.Body()
+	require.NoError(t, err)
+	require.Equal(t, `{"success":false}`, string(body))
+
+	// Failures are retried, the body of the last attempt is returned
+	resp, err = New(context.Background(), server.URL).SetSuccessFunc(success).FailOnError().SetRetry(2, time.Millisecond).Get()
+	require.NoError(t, err)
+	body, err = resp.Body()
+	require.NoError(t, err)
+	require.Equal(t, `{"success":true}`, string(body))
+	require.Equal(t, int32(3), atomic.LoadInt32(&requests))
+}
+
+func TestChainErr(t *testing.T) {
+	r := New(context.Background(), "http://example.com").
+		SetForm([]map[string]string{{"file": "/wrong/path"}}, nil).
+		SetProxy("ftp://proxy.com")
+
+	// The first failing setter is returned with its name
+	require.Error(t, r.Err())
+	require.True(t, strings.HasPrefix(r.Err().Error(), "SetForm: "))
+	require.Contains(t, r.Err().Error(), "/wrong/path")
+	require.True(t, errors.Is(r.Err(), os.ErrNotExist))
+
+	resp, err := r.Get()
+	require.Equal(t, r.Err(), err)
+	require.Nil(t, resp)
+	require.NoError(t, resp.Err())
+
+	r = New(context.Background(), "http://example.com").SetFormFields([]FormField{{Name: "file", Value: "/wrong/path"}}, nil)
+	require.True(t, strings.HasPrefix(r.Err().Error(), "SetFormFields: "))
+
+	require.NoError(t, New(context.Background(), "http://example.com").Err())
+}
+
+func TestResponseErr(t *testing.T) {
+
+	// Start a local HTTP server
+	server := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			rw.WriteHeader(http.StatusInternalServerError)
+		}),
+	)
+	defer server.Close()
+
+	resp, err := New(context.Background(), server.URL).FailOnError().Get()
+	require.Error(t, err)
+	require.Equal(t, err, resp.Err())
+
+	resp, err = New(context.Background(), server.URL).Get()
+	require.NoError(t, err)
+	require.NoError(t, resp.Err())
+}
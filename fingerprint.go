@@ -0,0 +1,62 @@
+package httpreq
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// defaultFingerprintExclude are headers differing between identical requests
+var defaultFingerprintExclude = []string{"Date", idempotencyKeyHeader}
+
+// SetFingerprintExclude excludes headers from Fingerprint in addition to Date and Idempotency-Key,
+// i.e. request IDs or nonces which differ between identical requests
+func (r *Req) SetFingerprintExclude(headers ...string) *Req {
+	r.fingerprintExclude = append(r.fingerprintExclude, headers...)
+	return r
+}
+
+// Fingerprint returns a stable hash of the method, URL, sorted headers and body of the request,
+// so identical requests can be correlated in logs and caches. It returns an empty string if the
+// request can't be built. Like ToCurl nothing is consumed, so bodies known only when sending
+// (SetBodyFunc, SetBodyFromRequest...) aren't part of the hash.
+func (r *Req) Fingerprint() string {
+	if r.err != nil {
+		r.log().Errorf("Can't fingerprint request Error: %v", r.err)
+		return ""
+	}
+
+	req, err := r.baseRequest(r.request.Method)
+	if err != nil {
+		r.log().Errorf("Can't fingerprint request Error: %v", err)
+		return ""
+	}
+
+	exclude := make(map[string]bool)
+	for _, name := range append(defaultFingerprintExclude, r.fingerprintExclude...) {
+		exclude[http.CanonicalHeaderKey(name)] = true
+	}
+
+	h := sha256.New()
+	_, _ = io.WriteString(h, req.Method+"\n"+req.URL.String()+"\n")
+
+	names := make([]string, 0, len(req.Header))
+	for name := range req.Header {
+		if !exclude[http.CanonicalHeaderKey(name)] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		_, _ = io.WriteString(h, http.CanonicalHeaderKey(name)+": "+strings.Join(req.Header[name], ", ")+"\n")
+	}
+
+	// Hash the body separately so it can't be confused with headers
+	body := sha256.Sum256(r.body)
+	_, _ = h.Write(body[:])
+
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,53 @@
+package httpreq
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFingerprint(t *testing.T) {
+	newReq := func() *Req {
+		return New(context.Background(), "http://example.com/path").
+			SetMethod("POST").
+			SetParam("a", "1").
+			SetHeaders(map[string]string{"X-Test": "value", "Date": "Mon, 01 Jan 2024 00:00:00 GMT"}).
+			AutoIdempotencyKey().
+			SetBody([]byte("body"))
+	}
+
+	fingerprint := newReq().Fingerprint()
+	require.Len(t, fingerprint, 64)
+	require.Equal(t, fingerprint, newReq().Fingerprint())
+
+	// Volatile headers are excluded
+	require.Equal(t, fingerprint, newReq().SetHeaders(map[string]string{"Date": "Tue, 02 Jan 2024 00:00:00 GMT"}).Fingerprint())
+	require.Equal(t, fingerprint, newReq().SetFingerprintExclude("X-Request-ID").SetHeaders(map[string]string{"X-Request-ID": "1"}).Fingerprint())
+
+	// Changes in any part
+	require.NotEqual(t, fingerprint, newReq().SetHeaders(map[string]string{"X-Test": "other"}).Fingerprint())
+	require.NotEqual(t, fingerprint, newReq().SetHeaders(map[string]string{"X-Request-ID": "1"}).Fingerprint())
+	require.NotEqual(t, fingerprint, newReq().SetParam("a", "2").Fingerprint())
+	require.NotEqual(t, fingerprint, newReq().SetMethod("PUT").Fingerprint())
+	require.NotEqual(t, fingerprint, newReq().SetBody([]byte("other")).Fingerprint())
+
+	require.Empty(t, New(context.Background(), "%").Fingerprint())
+
+	// One-shot bodies and token sources aren't used
+	in := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("incoming"))
+	ts := &countingTokenSource{}
+	r := New(context.Background(), "http://example.com/path").SetTokenSource(ts).SetBodyFromRequest(in)
+	require.Equal(t, r.Fingerprint(), r.Fingerprint())
+	require.Zero(t, ts.calls)
+
+	req, err := r.Request()
+	require.NoError(t, err)
+	body, err := ioutil.ReadAll(req.Body)
+	require.NoError(t, err)
+	require.Equal(t, "incoming", string(body))
+}
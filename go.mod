@@ -2,4 +2,13 @@ module github.com/binalyze/httpreq
 
 go 1.16
 
-require github.com/stretchr/testify v1.7.0
+require (
+	github.com/fxamacker/cbor/v2 v2.3.0
+	github.com/prometheus/client_golang v1.11.1
+	github.com/stretchr/testify v1.7.0
+	golang.org/x/crypto v0.0.0-20200622213623-75b288015ac9
+	golang.org/x/oauth2 v0.0.0-20190226205417-e64efc72b421
+	golang.org/x/sync v0.0.0-20201207232520-09787c993a3a
+	golang.org/x/text v0.3.7
+	google.golang.org/protobuf v1.26.0
+)
@@ -0,0 +1,159 @@
+package httpreq
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// SetHeadersFromStruct sets request headers from the fields of a struct tagged with `header:"X-Name"`.
+// Fields of scalar types (string, bool, numbers or pointers to them) are supported, fields with
+// the omitempty option (`header:"X-Name,omitempty"`) are skipped when they have the zero value.
+func (r *Req) SetHeadersFromStruct(v interface{}) *Req {
+
+	// If there is an error in chain, then do nothing and return early
+	if r.err != nil {
+		return r
+	}
+
+	headers, err := structHeaders(v)
+	if err != nil {
+		r.log().Errorf("Can't set headers from struct Error: %v", err)
+		r.setErr("SetHeadersFromStruct", err)
+		return r
+	}
+
+	for _, h := range headers {
+		r.request.Header.Set(h[0], h[1])
+	}
+	return r
+}
+
+// structHeaders returns name and value pairs of the tagged fields of v
+func structHeaders(v interface{}) ([][2]string, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil, fmt.Errorf("header struct is nil")
+		}
+		rv = rv.Elem()
+	}
+
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("header struct must be a struct, got %T", v)
+	}
+
+	var headers [][2]string
+
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+
+		tag, ok := field.Tag.Lookup("header")
+		if !ok || tag == "-" || field.PkgPath != "" {
+			continue
+		}
+
+		name, options := tag, ""
+		if i := strings.Index(tag, ","); i >= 0 {
+			name, options = tag[:i], tag[i+1:]
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		fv := rv.Field(i)
+		if options == "omitempty" && fv.IsZero() {
+			continue
+		}
+
+		// Nil pointers have no value to send
+		if fv.Kind() == reflect.Ptr {
+			if fv.IsNil() {
+				continue
+			}
+			fv = fv.Elem()
+		}
+
+		value, err := headerValue(fv)
+		if err != nil {
+			return nil, fmt.Errorf("header field %s: %w", field.Name, err)
+		}
+
+		headers = append(headers, [2]string{name, value})
+	}
+
+	return headers, nil
+}
+
+// headerValue formats a scalar value for a header
+func headerValue(v reflect.Value) (string, error) {
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, v.Type().Bits()), nil
+	}
+	return "", fmt.Errorf("unsupported type %s", v.Type())
+}
+
+// FilteredHeaders returns a copy of the response headers with only the allowed headers,
+// e.g. for forwarding them to another service. Names are case insensitive.
+func (r *Response) FilteredHeaders(allow []string) http.Header {
+	filtered := make(http.Header)
+	for _, name := range allow {
+		if values := r.Headers().Values(name); len(values) > 0 {
+			filtered[http.CanonicalHeaderKey(name)] = append([]string(nil), values...)
+		}
+	}
+	return filtered
+}
+
+// RedactedHeaders returns a copy of the response headers with the values of the denied headers
+// replaced by REDACTED, for logging them without secrets. Names are case insensitive.
+func (r *Response) RedactedHeaders(deny []string) http.Header {
+	headers := r.Headers().Clone()
+	if headers == nil {
+		return make(http.Header)
+	}
+	for _, name := range deny {
+		if _, ok := headers[http.CanonicalHeaderKey(name)]; ok {
+			headers[http.CanonicalHeaderKey(name)] = []string{redacted}
+		}
+	}
+	return headers
+}
+
+// RawHeaderBytes returns the response headers serialized in wire format ("Name: value\r\n" lines)
+// for logging or forensics, nil if there is no response
+func (r *Response) RawHeaderBytes() []byte {
+	headers := r.Headers()
+	if headers == nil {
+		return nil
+	}
+
+	var b bytes.Buffer
+	if err := headers.Write(&b); err != nil {
+		r.log().Errorf("Can't serialize response headers Error: %v", err)
+		return nil
+	}
+	return b.Bytes()
+}
+
+// HeaderCount returns the number of response header values, repeated headers count once per value
+func (r *Response) HeaderCount() int {
+	count := 0
+	for _, values := range r.Headers() {
+		count += len(values)
+	}
+	return count
+}
@@ -0,0 +1,94 @@
+package httpreq
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetHeadersFromStruct(t *testing.T) {
+	limit := 10
+
+	headers := struct {
+		RequestID string  `header:"X-Request-ID"`
+		Retry     bool    `header:"X-Retry"`
+		Limit     *int    `header:"X-Limit"`
+		Ratio     float64 `header:"X-Ratio"`
+		Empty     string  `header:"X-Empty,omitempty"`
+		Missing   *int    `header:"X-Missing"`
+		Ignored   string  `header:"-"`
+		Untagged  string
+	}{
+		RequestID: "abc-123",
+		Retry:     true,
+		Limit:     &limit,
+		Ratio:     0.5,
+		Ignored:   "ignored",
+		Untagged:  "untagged",
+	}
+
+	r := New(context.Background(), "").SetHeadersFromStruct(&headers)
+	require.NoError(t, r.err)
+
+	require.Equal(t, "abc-123", r.request.Header.Get("X-Request-ID"))
+	require.Equal(t, "true", r.request.Header.Get("X-Retry"))
+	require.Equal(t, "10", r.request.Header.Get("X-Limit"))
+	require.Equal(t, "0.5", r.request.Header.Get("X-Ratio"))
+	require.Len(t, r.request.Header, 4)
+}
+
+func TestSetHeadersFromStructError(t *testing.T) {
+	r := New(context.Background(), "").SetHeadersFromStruct("not a struct")
+	require.Error(t, r.err)
+
+	r = New(context.Background(), "").SetHeadersFromStruct(struct {
+		Values []string `header:"X-Values"`
+	}{})
+	require.Error(t, r.err)
+}
+
+func TestFilteredHeaders(t *testing.T) {
+	resp := NewResponse(&http.Response{Header: http.Header{
+		"Content-Type": []string{"application/json"},
+		"Set-Cookie":   []string{"a=1", "b=2"},
+		"X-Request-Id": []string{"id"},
+	}})
+
+	filtered := resp.FilteredHeaders([]string{"content-type", "X-Request-ID", "X-Missing"})
+	require.Equal(t, http.Header{
+		"Content-Type": []string{"application/json"},
+		"X-Request-Id": []string{"id"},
+	}, filtered)
+
+	// The copy doesn't change the response
+	filtered.Set("Content-Type", "text/plain")
+	require.Equal(t, "application/json", resp.Headers().Get("Content-Type"))
+
+	redactedHeaders := resp.RedactedHeaders([]string{"set-cookie", "Authorization"})
+	require.Equal(t, []string{"REDACTED"}, redactedHeaders.Values("Set-Cookie"))
+	require.Equal(t, "id", redactedHeaders.Get("X-Request-Id"))
+	require.Empty(t, redactedHeaders.Values("Authorization"))
+	require.Equal(t, []string{"a=1", "b=2"}, resp.Headers().Values("Set-Cookie"))
+
+	require.Empty(t, NewResponse(nil).FilteredHeaders([]string{"Content-Type"}))
+}
+
+func TestRawHeaderBytes(t *testing.T) {
+	resp := NewResponse(&http.Response{Header: http.Header{
+		"Content-Type": []string{"application/json"},
+		"Set-Cookie":   []string{"a=1", "b=2"},
+	}})
+
+	raw := string(resp.RawHeaderBytes())
+	require.Contains(t, raw, "Content-Type: application/json\r\n")
+	require.Contains(t, raw, "Set-Cookie: a=1\r\nSet-Cookie: b=2\r\n")
+	require.Equal(t, 3, resp.HeaderCount())
+
+	// Nil safe
+	var nilResp *Response
+	require.Nil(t, nilResp.RawHeaderBytes())
+	require.Zero(t, nilResp.HeaderCount())
+	require.Nil(t, NewResponse(nil).RawHeaderBytes())
+}
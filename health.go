@@ -0,0 +1,65 @@
+package httpreq
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"time"
+)
+
+// HealthResult is the result of SelfTest
+type HealthResult struct {
+	// Healthy is true when the target responded with a status code below 500
+	Healthy bool
+
+	// StatusCode is the status code of the response, 0 if there was no response
+	StatusCode int
+
+	// Latency is the time until the response headers were received
+	Latency time.Duration
+
+	// TLSVersion is the negotiated TLS version, i.e. tls.VersionTLS13, 0 without TLS
+	TLSVersion uint16
+
+	// Err is the error if the target couldn't be reached
+	Err error
+}
+
+// TLSVersionName returns the name of the negotiated TLS version, i.e. "TLS 1.3", empty without TLS
+func (h HealthResult) TLSVersionName() string {
+	switch h.TLSVersion {
+	case tls.VersionTLS10:
+		return "TLS 1.0"
+	case tls.VersionTLS11:
+		return "TLS 1.1"
+	case tls.VersionTLS12:
+		return "TLS 1.2"
+	case tls.VersionTLS13:
+		return "TLS 1.3"
+	}
+	return ""
+}
+
+// SelfTest sends an OPTIONS request to url to verify connectivity and TLS, i.e. as a readiness probe
+func SelfTest(ctx context.Context, url string) HealthResult {
+	return New(ctx, url).SelfTest()
+}
+
+// SelfTest sends the request with the OPTIONS method to verify connectivity and TLS
+// with the settings of the request, like SetTLSConfig or SetProxy
+func (r *Req) SelfTest() HealthResult {
+	start := r.clock.Now()
+	resp, err := r.send(http.MethodOptions)
+	result := HealthResult{Latency: r.clock.Now().Sub(start), Err: err}
+	if err != nil {
+		return result
+	}
+	defer resp.Close()
+
+	result.StatusCode = resp.StatusCode()
+	result.Healthy = result.StatusCode < http.StatusInternalServerError
+	if state := resp.Response().TLS; state != nil {
+		result.TLSVersion = state.Version
+	}
+	return result
+}
@@ -0,0 +1,46 @@
+package httpreq
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSelfTest(t *testing.T) {
+
+	// Start a local HTTPS server
+	server := httptest.NewTLSServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			require.Equal(t, http.MethodOptions, req.Method)
+			rw.Header().Set("Allow", "GET, OPTIONS")
+		}),
+	)
+	defer server.Close()
+
+	result := New(context.Background(), server.URL).SetTLSConfig(&tls.Config{InsecureSkipVerify: true}).SelfTest()
+	require.NoError(t, result.Err)
+	require.True(t, result.Healthy)
+	require.Equal(t, http.StatusOK, result.StatusCode)
+	require.Greater(t, int64(result.Latency), int64(0))
+	require.Equal(t, uint16(tls.VersionTLS13), result.TLSVersion)
+	require.Equal(t, "TLS 1.3", result.TLSVersionName())
+
+	// The certificate isn't trusted by default
+	result = SelfTest(context.Background(), server.URL)
+	require.Error(t, result.Err)
+	require.False(t, result.Healthy)
+
+	// Unreachable
+	unreachable := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	unreachable.Close()
+
+	result = SelfTest(context.Background(), unreachable.URL)
+	require.Error(t, result.Err)
+	require.False(t, result.Healthy)
+	require.Zero(t, result.StatusCode)
+	require.Empty(t, result.TLSVersionName())
+}
@@ -0,0 +1,124 @@
+package httpreq
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// SetHedging sends up to maxCopies identical requests, starting a new one every delay while
+// none has responded, and returns the first response, canceling the others. It reduces tail
+// latency against replicated backends. Only idempotent methods are hedged.
+func (r *Req) SetHedging(delay time.Duration, maxCopies int) *Req {
+	r.hedgeDelay = delay
+	r.hedgeCopies = maxCopies
+	return r
+}
+
+// isIdempotent reports whether sending a request with method more than once has the same effect as once
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// hedgeResult is the outcome of one copy of a hedged request
+type hedgeResult struct {
+	index  int
+	resp   *http.Response
+	err    error
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// hedge sends copies of req as configured by SetHedging and returns the first response
+func (r *Req) hedge(req *http.Request) (*http.Response, error) {
+
+	// Each copy needs its own body
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return r.clientDo(req)
+	}
+	if _, shared := req.Body.(sharedBody); shared {
+		return r.clientDo(req)
+	}
+
+	results := make(chan hedgeResult, r.hedgeCopies)
+	var cancels []context.CancelFunc
+
+	start := func() error {
+		ctx, cancel := context.WithCancel(req.Context())
+		index := len(cancels)
+		cancels = append(cancels, cancel)
+
+		// The first copy sends the body of req, the others get their own
+		hedged := req.Clone(ctx)
+		if index > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				cancel()
+				return err
+			}
+			hedged.Body = body
+		}
+
+		go func() {
+			resp, err := r.clientDo(hedged)
+			results <- hedgeResult{index: index, resp: resp, err: err, ctx: ctx, cancel: cancel}
+		}()
+		return nil
+	}
+
+	if err := start(); err != nil {
+		return nil, err
+	}
+	started, pending := 1, 1
+
+	timer := time.NewTimer(r.hedgeDelay)
+	defer timer.Stop()
+
+	for {
+		select {
+		case res := <-results:
+			pending--
+
+			if res.err != nil {
+				res.cancel()
+				if pending == 0 {
+					return nil, res.err
+				}
+				continue
+			}
+
+			// Cancel the other copies and release their responses
+			for i, cancel := range cancels {
+				if i != res.index {
+					cancel()
+				}
+			}
+			go func(pending int) {
+				for ; pending > 0; pending-- {
+					if other := <-results; other.resp != nil {
+						_ = other.resp.Body.Close()
+					}
+				}
+			}(pending)
+
+			// Canceling the winner's context is deferred until its body is closed
+			res.resp.Body = &cancelBody{ReadCloser: res.resp.Body, ctx: res.ctx, cancel: res.cancel}
+			return res.resp, nil
+
+		case <-timer.C:
+			if started < r.hedgeCopies {
+				if err := start(); err != nil {
+					r.log().Errorf("Can't start hedged request Error: %v", err)
+				} else {
+					started++
+					pending++
+				}
+				timer.Reset(r.hedgeDelay)
+			}
+		}
+	}
+}
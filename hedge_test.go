@@ -0,0 +1,120 @@
+package httpreq
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetHedging(t *testing.T) {
+	var requests int32
+	canceled := make(chan struct{}, 1)
+
+	// Start a local HTTP server which is slow for the first request only
+	server := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+
+			// Every copy has the whole body
+			body, err := ioutil.ReadAll(req.Body)
+			require.NoError(t, err)
+			require.Equal(t, "body", string(body))
+
+			if atomic.AddInt32(&requests, 1) == 1 {
+				select {
+				case <-req.Context().Done():
+					canceled <- struct{}{}
+				case <-time.After(2 * time.Second):
+				}
+				return
+			}
+			_, err = rw.Write([]byte(responseData))
+			require.NoError(t, err)
+		}),
+	)
+	defer server.Close()
+
+	start := time.Now()
+	resp, err := New(context.Background(), server.URL).SetHedging(50*time.Millisecond, 3).SetBody([]byte("body")).Put()
+	require.NoError(t, err)
+
+	body, err := resp.Body()
+	require.NoError(t, err)
+	require.Equal(t, responseData, string(body))
+	require.Less(t, int64(time.Since(start)), int64(time.Second))
+
+	// Slow copy is canceled
+	select {
+	case <-canceled:
+	case <-time.After(time.Second):
+		t.Fatal("slow request is not canceled")
+	}
+	require.Equal(t, int32(2), atomic.LoadInt32(&requests))
+}
+
+func TestSetHedgingBodyClosed(t *testing.T) {
+	var opened, closed int32
+
+	// Start a local HTTP server
+	server := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			_, _ = ioutil.ReadAll(req.Body)
+		}),
+	)
+	defer server.Close()
+
+	resp, err := New(context.Background(), server.URL).
+		SetHedging(time.Second, 3).
+		SetBodyFunc(func() (io.ReadCloser, int64, error) {
+			atomic.AddInt32(&opened, 1)
+			return &closeCounter{Reader: strings.NewReader("body"), closed: &closed}, 4, nil
+		}).
+		Put()
+	require.NoError(t, err)
+	require.NoError(t, resp.Close())
+
+	// Every body produced is sent and closed
+	require.Equal(t, int32(1), atomic.LoadInt32(&opened))
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&closed) == 1 }, time.Second, 10*time.Millisecond)
+}
+
+// closeCounter counts the calls to Close
+type closeCounter struct {
+	io.Reader
+	closed *int32
+}
+
+func (c *closeCounter) Close() error {
+	atomic.AddInt32(c.closed, 1)
+	return nil
+}
+
+func TestSetHedgingNotIdempotent(t *testing.T) {
+	var requests int32
+
+	// Start a local HTTP server
+	server := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			atomic.AddInt32(&requests, 1)
+			time.Sleep(150 * time.Millisecond)
+		}),
+	)
+	defer server.Close()
+
+	_, err := New(context.Background(), server.URL).SetHedging(10*time.Millisecond, 3).SetBody([]byte("body")).Post()
+	require.NoError(t, err)
+	require.Equal(t, int32(1), atomic.LoadInt32(&requests))
+}
+
+func TestSetHedgingError(t *testing.T) {
+	// wrong-host should causes request error
+	_, err := New(context.Background(), "wrong-host").SetHedging(10*time.Millisecond, 3).Get()
+	require.Error(t, err)
+}
@@ -0,0 +1,50 @@
+package httpreq
+
+import (
+	"crypto/rand"
+	"fmt"
+	"net/http"
+)
+
+// idempotencyKeyHeader is the header used by Stripe and similar APIs to detect duplicate requests
+const idempotencyKeyHeader = "Idempotency-Key"
+
+// SetIdempotencyKey sets the Idempotency-Key header, servers use it to avoid duplicate side effects
+// when a request is sent again. The same key is sent by every attempt of the request.
+func (r *Req) SetIdempotencyKey(key string) *Req {
+	r.request.Header.Set(idempotencyKeyHeader, key)
+	return r
+}
+
+// AutoIdempotencyKey generates a random UUID as the Idempotency-Key of each POST request
+// unless a key is set with SetIdempotencyKey. The key is generated once per send
+// and reused by every attempt of it.
+func (r *Req) AutoIdempotencyKey() *Req {
+	r.autoIdempotencyKey = true
+	return r
+}
+
+// setIdempotencyKey generates the Idempotency-Key of req if enabled by AutoIdempotencyKey
+func (r *Req) setIdempotencyKey(req *http.Request) error {
+	if !r.autoIdempotencyKey || req.Method != http.MethodPost || req.Header.Get(idempotencyKeyHeader) != "" {
+		return nil
+	}
+
+	key, err := newUUID()
+	if err != nil {
+		return err
+	}
+	req.Header.Set(idempotencyKeyHeader, key)
+	return nil
+}
+
+// newUUID returns a random (version 4) UUID
+func newUUID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
@@ -0,0 +1,56 @@
+package httpreq
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAutoIdempotencyKey(t *testing.T) {
+	var keys []string
+
+	// Start a local HTTP server which asks for authentication, so requests are sent twice
+	server := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			keys = append(keys, req.Header.Get(idempotencyKeyHeader))
+			if req.Header.Get("Authorization") == "" {
+				rw.Header().Set("WWW-Authenticate", `Digest realm="test", nonce="nonce"`)
+				rw.WriteHeader(http.StatusUnauthorized)
+			}
+		}),
+	)
+	defer server.Close()
+
+	r := New(context.Background(), server.URL).SetDigestAuth("user", "pass").SetBody([]byte("body")).AutoIdempotencyKey()
+
+	resp, err := r.Post()
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+
+	// Attempts of a request share the key
+	require.Len(t, keys, 2)
+	require.Regexp(t, regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`), keys[0])
+	require.Equal(t, keys[0], keys[1])
+
+	// Another request gets a new key
+	_, err = r.Post()
+	require.NoError(t, err)
+	require.Len(t, keys, 4)
+	require.NotEqual(t, keys[0], keys[2])
+	require.Equal(t, keys[2], keys[3])
+
+	// Only POST requests get a key
+	_, err = New(context.Background(), server.URL).AutoIdempotencyKey().Get()
+	require.NoError(t, err)
+	require.Equal(t, "", keys[4])
+
+	// A set key is kept
+	keys = nil
+	_, err = New(context.Background(), server.URL).SetDigestAuth("user", "pass").SetIdempotencyKey("key").AutoIdempotencyKey().Post()
+	require.NoError(t, err)
+	require.Equal(t, []string{"key", "key"}, keys)
+}
@@ -0,0 +1,112 @@
+package httpreq
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// JSONPath decodes the JSON response body and returns the value at a dotted path with
+// array indexes, e.g. "data.items[0].id", without declaring a struct.
+// Objects are map[string]interface{} and arrays []interface{} as with json.Unmarshal.
+// An empty path returns the whole body.
+func (r *Response) JSONPath(path string) (interface{}, error) {
+	var v interface{}
+	if err := r.DecodeJSON(&v); err != nil {
+		return nil, err
+	}
+
+	value, err := lookupJSONPath(v, path)
+	if err != nil {
+		r.log().Errorf("Can't get JSON path %q Error: %v", path, err)
+		return nil, err
+	}
+	return value, nil
+}
+
+// lookupJSONPath returns the value at path in v
+func lookupJSONPath(v interface{}, path string) (interface{}, error) {
+	keys, err := parseJSONPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, key := range keys {
+		switch node := v.(type) {
+		case map[string]interface{}:
+			value, ok := node[key.name]
+			if key.index >= 0 || !ok {
+				return nil, fmt.Errorf("key not found: %s", jsonPathString(keys[:i+1]))
+			}
+			v = value
+		case []interface{}:
+			if key.index < 0 || key.index >= len(node) {
+				return nil, fmt.Errorf("index not found: %s", jsonPathString(keys[:i+1]))
+			}
+			v = node[key.index]
+		default:
+			return nil, fmt.Errorf("not an object or array: %s", jsonPathString(keys[:i]))
+		}
+	}
+	return v, nil
+}
+
+// jsonPathKey is a path segment, an object key or an array index
+type jsonPathKey struct {
+	name  string
+	index int
+}
+
+// parseJSONPath splits a path like "data.items[0].id" into its segments
+func parseJSONPath(path string) ([]jsonPathKey, error) {
+	var keys []jsonPathKey
+
+	for _, part := range strings.Split(path, ".") {
+		if path == "" {
+			break
+		}
+
+		name := part
+		if i := strings.IndexByte(part, '['); i >= 0 {
+			name = part[:i]
+		}
+		if part == "" {
+			return nil, fmt.Errorf("invalid JSON path: %q", path)
+		}
+		if name != "" {
+			keys = append(keys, jsonPathKey{name: name, index: -1})
+		}
+
+		// Array indexes, possibly nested like "[0][1]"
+		rest := part[len(name):]
+		for rest != "" {
+			end := strings.IndexByte(rest, ']')
+			if rest[0] != '[' || end < 0 {
+				return nil, fmt.Errorf("invalid JSON path: %q", path)
+			}
+			index, err := strconv.Atoi(rest[1:end])
+			if err != nil || index < 0 {
+				return nil, fmt.Errorf("invalid JSON path index: %q", path)
+			}
+			keys = append(keys, jsonPathKey{index: index})
+			rest = rest[end+1:]
+		}
+	}
+	return keys, nil
+}
+
+// jsonPathString formats path segments back to a path
+func jsonPathString(keys []jsonPathKey) string {
+	var b strings.Builder
+	for _, key := range keys {
+		if key.index >= 0 {
+			fmt.Fprintf(&b, "[%d]", key.index)
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteByte('.')
+		}
+		b.WriteString(key.name)
+	}
+	return b.String()
+}
@@ -0,0 +1,72 @@
+package httpreq
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONPath(t *testing.T) {
+
+	// Start a local HTTP server
+	server := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			rw.Header().Set("Content-Type", "application/json")
+			_, err := rw.Write([]byte(`{"data":{"items":[{"id":1,"tags":["a","b"]},{"id":2,"owner":{"name":"John"}}]}}`))
+			require.NoError(t, err)
+		}),
+	)
+	defer server.Close()
+
+	resp, err := New(context.Background(), server.URL).Get()
+	require.NoError(t, err)
+
+	value, err := resp.JSONPath("data.items[0].id")
+	require.NoError(t, err)
+	require.Equal(t, float64(1), value)
+
+	value, err = resp.JSONPath("data.items[1].owner.name")
+	require.NoError(t, err)
+	require.Equal(t, "John", value)
+
+	value, err = resp.JSONPath("data.items[0].tags[1]")
+	require.NoError(t, err)
+	require.Equal(t, "b", value)
+
+	value, err = resp.JSONPath("data.items[1].owner")
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{"name": "John"}, value)
+
+	value, err = resp.JSONPath("data.items")
+	require.NoError(t, err)
+	require.Len(t, value, 2)
+
+	// Missing values and invalid paths
+	_, err = resp.JSONPath("data.items[2].id")
+	require.EqualError(t, err, "index not found: data.items[2]")
+
+	_, err = resp.JSONPath("data.missing")
+	require.EqualError(t, err, "key not found: data.missing")
+
+	_, err = resp.JSONPath("data.items[0].id.x")
+	require.EqualError(t, err, "not an object or array: data.items[0].id")
+
+	_, err = resp.JSONPath("data..items")
+	require.Error(t, err)
+
+	_, err = resp.JSONPath("data.items[x]")
+	require.Error(t, err)
+}
+
+func TestParseJSONPath(t *testing.T) {
+	keys, err := parseJSONPath("")
+	require.NoError(t, err)
+	require.Empty(t, keys)
+
+	keys, err = parseJSONPath("[0][1].a")
+	require.NoError(t, err)
+	require.Equal(t, []jsonPathKey{{index: 0}, {index: 1}, {name: "a", index: -1}}, keys)
+}
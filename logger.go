@@ -1,8 +1,11 @@
 package httpreq
 
 import (
+	"fmt"
 	"log"
 	"os"
+	"sort"
+	"strings"
 )
 
 type Logger interface {
@@ -18,6 +21,12 @@ type Logger interface {
 	Fatalf(format string, args ...interface{})
 }
 
+// FieldLogger is a Logger which supports structured fields
+type FieldLogger interface {
+	Logger
+	WithFields(fields map[string]interface{}) Logger
+}
+
 type BuiltinLogger struct {
 	logger *log.Logger
 }
@@ -65,3 +74,77 @@ func (l *BuiltinLogger) Fatal(args ...interface{}) {
 func (l *BuiltinLogger) Fatalf(format string, args ...interface{}) {
 	l.logger.Printf(format, args...)
 }
+
+func (l *BuiltinLogger) WithFields(fields map[string]interface{}) Logger {
+	return &fieldLogger{logger: l, fields: formatFields(fields)}
+}
+
+// withFields attaches fields to l, falling back to appending them to messages
+// when l is not a FieldLogger
+func withFields(l Logger, fields map[string]interface{}) Logger {
+	if fl, ok := l.(FieldLogger); ok {
+		return fl.WithFields(fields)
+	}
+	return &fieldLogger{logger: l, fields: formatFields(fields)}
+}
+
+// formatFields formats fields as key=value pairs sorted by key
+func formatFields(fields map[string]interface{}) string {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, fmt.Sprintf("%s=%v", k, fields[k]))
+	}
+	return strings.Join(pairs, " ")
+}
+
+// fieldLogger appends formatted fields to every message of the wrapped logger
+type fieldLogger struct {
+	logger Logger
+	fields string
+}
+
+func (l *fieldLogger) Debug(args ...interface{}) {
+	l.logger.Debug(append(args, l.fields)...)
+}
+
+func (l *fieldLogger) Debugf(format string, args ...interface{}) {
+	l.logger.Debugf(format+" %s", append(args, l.fields)...)
+}
+
+func (l *fieldLogger) Info(args ...interface{}) {
+	l.logger.Info(append(args, l.fields)...)
+}
+
+func (l *fieldLogger) Infof(format string, args ...interface{}) {
+	l.logger.Infof(format+" %s", append(args, l.fields)...)
+}
+
+func (l *fieldLogger) Warn(args ...interface{}) {
+	l.logger.Warn(append(args, l.fields)...)
+}
+
+func (l *fieldLogger) Warnf(format string, args ...interface{}) {
+	l.logger.Warnf(format+" %s", append(args, l.fields)...)
+}
+
+func (l *fieldLogger) Error(args ...interface{}) {
+	l.logger.Error(append(args, l.fields)...)
+}
+
+func (l *fieldLogger) Errorf(format string, args ...interface{}) {
+	l.logger.Errorf(format+" %s", append(args, l.fields)...)
+}
+
+func (l *fieldLogger) Fatal(args ...interface{}) {
+	l.logger.Fatal(append(args, l.fields)...)
+}
+
+func (l *fieldLogger) Fatalf(format string, args ...interface{}) {
+	l.logger.Fatalf(format+" %s", append(args, l.fields)...)
+}
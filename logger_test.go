@@ -0,0 +1,97 @@
+package httpreq
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// captureLogger records every message logged through it
+type captureLogger struct {
+	mu       sync.Mutex
+	messages []string
+}
+
+func (l *captureLogger) add(msg string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.messages = append(l.messages, msg)
+}
+
+func (l *captureLogger) all() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return strings.Join(l.messages, "\n")
+}
+
+func (l *captureLogger) Debug(args ...interface{}) { l.add(fmt.Sprintln(args...)) }
+func (l *captureLogger) Debugf(format string, args ...interface{}) {
+	l.add(fmt.Sprintf(format, args...))
+}
+func (l *captureLogger) Info(args ...interface{}) { l.add(fmt.Sprintln(args...)) }
+func (l *captureLogger) Infof(format string, args ...interface{}) {
+	l.add(fmt.Sprintf(format, args...))
+}
+func (l *captureLogger) Warn(args ...interface{}) { l.add(fmt.Sprintln(args...)) }
+func (l *captureLogger) Warnf(format string, args ...interface{}) {
+	l.add(fmt.Sprintf(format, args...))
+}
+func (l *captureLogger) Error(args ...interface{}) { l.add(fmt.Sprintln(args...)) }
+func (l *captureLogger) Errorf(format string, args ...interface{}) {
+	l.add(fmt.Sprintf(format, args...))
+}
+func (l *captureLogger) Fatal(args ...interface{}) { l.add(fmt.Sprintln(args...)) }
+func (l *captureLogger) Fatalf(format string, args ...interface{}) {
+	l.add(fmt.Sprintf(format, args...))
+}
+
+// fieldCaptureLogger is a captureLogger which supports structured fields
+type fieldCaptureLogger struct {
+	captureLogger
+	fields map[string]interface{}
+}
+
+func (l *fieldCaptureLogger) WithFields(fields map[string]interface{}) Logger {
+	l.fields = fields
+	return l
+}
+
+// setTestLogger replaces the package logger until the test finishes
+func setTestLogger(t *testing.T, l Logger) {
+	t.Helper()
+
+	old := logger
+	logger = l
+	t.Cleanup(func() { logger = old })
+}
+
+func TestWithLogFields(t *testing.T) {
+	l := &captureLogger{}
+	setTestLogger(t, l)
+
+	// wrong-host should causes request error which is logged
+	_, err := New(context.Background(), "wrong-host").
+		WithLogFields(map[string]interface{}{"request_id": "abc-123", "url": "wrong-host"}).
+		Get()
+	require.Error(t, err)
+
+	require.Contains(t, l.all(), "request_id=abc-123 url=wrong-host")
+}
+
+func TestWithLogFieldsFieldLogger(t *testing.T) {
+	l := &fieldCaptureLogger{}
+	setTestLogger(t, l)
+
+	_, err := New(context.Background(), "wrong-host").
+		WithLogFields(map[string]interface{}{"request_id": "abc-123"}).
+		Get()
+	require.Error(t, err)
+
+	require.Equal(t, "abc-123", l.fields["request_id"])
+	require.NotEmpty(t, l.all())
+	require.NotContains(t, l.all(), "request_id=")
+}
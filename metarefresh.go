@@ -0,0 +1,93 @@
+package httpreq
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var (
+	metaTagPattern    = regexp.MustCompile(`(?is)<meta\s[^>]*>`)
+	metaAttrPattern   = regexp.MustCompile(`(?is)([a-z-]+)\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s>]+))`)
+	refreshURLPattern = regexp.MustCompile(`(?is)^\s*\d*(?:\.\d*)?\s*[;,]?\s*(?:url\s*=\s*)?(.*?)\s*$`)
+)
+
+// FollowMetaRefresh is a get http request following up to maxHops redirects made with
+// <meta http-equiv="refresh"> in HTML pages, for pages redirecting without HTTP 3xx.
+// Refresh delays are ignored. It returns the first response without a meta refresh.
+func (r *Req) FollowMetaRefresh(maxHops int) (*Response, error) {
+	return r.exclusive(func() (*Response, error) {
+		return r.followMetaRefresh(maxHops)
+	})
+}
+
+// followMetaRefresh sends the requests of FollowMetaRefresh
+func (r *Req) followMetaRefresh(maxHops int) (*Response, error) {
+	resp, err := r.sendPrepared(http.MethodGet, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	for hops := 0; ; hops++ {
+		if !strings.Contains(strings.ToLower(resp.Headers().Get("Content-Type")), "html") {
+			return resp, nil
+		}
+
+		body, err := resp.Body()
+		if err != nil {
+			return nil, err
+		}
+
+		target, ok := metaRefreshURL(body)
+		if !ok {
+			return resp, nil
+		}
+		if hops == maxHops {
+			return nil, fmt.Errorf("stopped after %d meta refresh hops", maxHops)
+		}
+
+		// Relative to the URL of the page, it's unknown for responses made by an interceptor
+		base := &url.URL{}
+		if resp.Response().Request != nil {
+			base = resp.Response().Request.URL
+		}
+		next, err := base.Parse(target)
+		if err != nil {
+			r.log().Errorf("Error parsing meta refresh URL: %s, %v", target, err)
+			return nil, err
+		}
+
+		resp, err = r.sendPrepared(http.MethodGet, func(req *http.Request) {
+			req.URL = next
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// metaRefreshURL returns the URL of the first meta refresh directive of an HTML page
+func metaRefreshURL(html []byte) (string, bool) {
+	for _, tag := range metaTagPattern.FindAll(html, -1) {
+		attrs := make(map[string]string)
+		for _, m := range metaAttrPattern.FindAllSubmatch(tag, -1) {
+			attrs[strings.ToLower(string(m[1]))] = string(m[2]) + string(m[3]) + string(m[4])
+		}
+		if !strings.EqualFold(attrs["http-equiv"], "refresh") {
+			continue
+		}
+
+		m := refreshURLPattern.FindStringSubmatch(attrs["content"])
+		if m == nil {
+			continue
+		}
+		target := strings.Trim(m[1], `'"`)
+		if target == "" {
+			continue
+		}
+		return target, true
+	}
+	return "", false
+}
@@ -0,0 +1,62 @@
+package httpreq
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFollowMetaRefresh(t *testing.T) {
+
+	// Start a local HTTP server redirecting with meta refresh
+	server := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			switch req.URL.Path {
+			case "/start":
+				rw.Header().Set("Content-Type", "text/html; charset=utf-8")
+				_, _ = rw.Write([]byte(`<html><head><META HTTP-EQUIV="Refresh" CONTENT="0; URL='next?a=1'"></head></html>`))
+			case "/next":
+				require.Equal(t, "1", req.URL.Query().Get("a"))
+				rw.Header().Set("Content-Type", "text/html")
+				_, _ = rw.Write([]byte(`<meta charset="utf-8"><meta http-equiv=refresh content="5;url=/final">`))
+			case "/loop":
+				rw.Header().Set("Content-Type", "text/html")
+				_, _ = rw.Write([]byte(`<meta http-equiv="refresh" content="0; url=/loop">`))
+			default:
+				_, _ = rw.Write([]byte(responseData))
+			}
+		}),
+	)
+	defer server.Close()
+
+	resp, err := New(context.Background(), server.URL+"/start").FollowMetaRefresh(5)
+	require.NoError(t, err)
+	require.Equal(t, "/final", resp.Response().Request.URL.Path)
+
+	body, err := resp.Body()
+	require.NoError(t, err)
+	require.Equal(t, responseData, string(body))
+
+	// Not enough hops
+	_, err = New(context.Background(), server.URL+"/start").FollowMetaRefresh(1)
+	require.Error(t, err)
+
+	_, err = New(context.Background(), server.URL+"/loop").FollowMetaRefresh(3)
+	require.Error(t, err)
+}
+
+func TestMetaRefreshURL(t *testing.T) {
+	target, ok := metaRefreshURL([]byte(`<meta content="3; URL=https://example.com/x" http-equiv="refresh" />`))
+	require.True(t, ok)
+	require.Equal(t, "https://example.com/x", target)
+
+	// Refreshing the same page isn't a redirect
+	_, ok = metaRefreshURL([]byte(`<meta http-equiv="refresh" content="30">`))
+	require.False(t, ok)
+
+	_, ok = metaRefreshURL([]byte(`<meta name="refresh" content="0; url=/x">`))
+	require.False(t, ok)
+}
@@ -0,0 +1,99 @@
+package httpreq
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// requestMetrics are the Prometheus collectors updated by requests, see WithMetrics
+type requestMetrics struct {
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+	inFlight *prometheus.GaugeVec
+}
+
+// WithMetrics records request counts, latencies and in-flight requests labeled by method and
+// status class (2xx, 4xx... or "error") to the registerer. Collectors already registered by
+// another request are reused, so the same registerer can be passed to every request.
+func (r *Req) WithMetrics(registerer prometheus.Registerer) *Req {
+	m, err := newRequestMetrics(registerer)
+	if err != nil {
+		r.log().Errorf("Can't register metrics Error: %v", err)
+		r.setErr("WithMetrics", err)
+		return r
+	}
+	r.metrics = m
+	return r
+}
+
+func newRequestMetrics(registerer prometheus.Registerer) (*requestMetrics, error) {
+	requests, err := registerCollector(registerer, prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "httpreq_requests_total",
+		Help: "Total number of HTTP requests by method and status class.",
+	}, []string{"method", "status_class"}))
+	if err != nil {
+		return nil, err
+	}
+
+	duration, err := registerCollector(registerer, prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "httpreq_request_duration_seconds",
+		Help:    "Duration of HTTP requests until the response headers are received.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "status_class"}))
+	if err != nil {
+		return nil, err
+	}
+
+	inFlight, err := registerCollector(registerer, prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "httpreq_requests_in_flight",
+		Help: "Number of HTTP requests waiting for a response by method.",
+	}, []string{"method"}))
+	if err != nil {
+		return nil, err
+	}
+
+	return &requestMetrics{
+		requests: requests.(*prometheus.CounterVec),
+		duration: duration.(*prometheus.HistogramVec),
+		inFlight: inFlight.(*prometheus.GaugeVec),
+	}, nil
+}
+
+// registerCollector registers c or returns the collector already registered with the same description
+func registerCollector(registerer prometheus.Registerer, c prometheus.Collector) (prometheus.Collector, error) {
+	if err := registerer.Register(c); err != nil {
+		var are prometheus.AlreadyRegisteredError
+		if errors.As(err, &are) {
+			return are.ExistingCollector, nil
+		}
+		return nil, err
+	}
+	return c, nil
+}
+
+// start records a request of method in flight and returns a function to record its outcome,
+// it does nothing if metrics are not enabled
+func (m *requestMetrics) start(method string) func(resp *http.Response, err error) {
+	if m == nil {
+		return func(*http.Response, error) {}
+	}
+
+	start := time.Now()
+	m.inFlight.WithLabelValues(method).Inc()
+
+	return func(resp *http.Response, err error) {
+		m.inFlight.WithLabelValues(method).Dec()
+
+		class := "error"
+		if err == nil {
+			class = fmt.Sprintf("%dxx", resp.StatusCode/100)
+		}
+
+		m.requests.WithLabelValues(method, class).Inc()
+		m.duration.WithLabelValues(method, class).Observe(time.Since(start).Seconds())
+	}
+}
@@ -0,0 +1,60 @@
+package httpreq
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithMetrics(t *testing.T) {
+
+	// Start a local HTTP server
+	server := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			if req.URL.Path == "/missing" {
+				rw.WriteHeader(http.StatusNotFound)
+			}
+		}),
+	)
+	defer server.Close()
+
+	registry := prometheus.NewRegistry()
+
+	for i := 0; i < 2; i++ {
+		_, err := New(context.Background(), server.URL).WithMetrics(registry).Get()
+		require.NoError(t, err)
+	}
+
+	_, err := New(context.Background(), server.URL+"/missing").WithMetrics(registry).Post()
+	require.NoError(t, err)
+
+	// wrong-host should causes request error
+	_, err = New(context.Background(), "wrong-host").WithMetrics(registry).Get()
+	require.Error(t, err)
+
+	m, err := newRequestMetrics(registry)
+	require.NoError(t, err)
+
+	require.Equal(t, 2.0, testutil.ToFloat64(m.requests.WithLabelValues(http.MethodGet, "2xx")))
+	require.Equal(t, 1.0, testutil.ToFloat64(m.requests.WithLabelValues(http.MethodPost, "4xx")))
+	require.Equal(t, 1.0, testutil.ToFloat64(m.requests.WithLabelValues(http.MethodGet, "error")))
+	require.Equal(t, 0.0, testutil.ToFloat64(m.inFlight.WithLabelValues(http.MethodGet)))
+
+	families, err := registry.Gather()
+	require.NoError(t, err)
+
+	var observations uint64
+	for _, family := range families {
+		if family.GetName() == "httpreq_request_duration_seconds" {
+			for _, metric := range family.GetMetric() {
+				observations += metric.GetHistogram().GetSampleCount()
+			}
+		}
+	}
+	require.Equal(t, uint64(4), observations)
+}
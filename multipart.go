@@ -0,0 +1,80 @@
+package httpreq
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"strings"
+)
+
+// ErrPartTooLarge is returned by Response.MultipartParts when a part exceeds Req.SetMaxPartSize
+var ErrPartTooLarge = errors.New("multipart response part is too large")
+
+// Part is a part of a multipart response body
+type Part struct {
+	Header   textproto.MIMEHeader
+	FormName string
+	FileName string
+	Data     []byte
+}
+
+// SetMaxPartSize limits the size of each part read by Response.MultipartParts,
+// so a single oversized part can't exhaust memory. 0 means no limit.
+func (r *Req) SetMaxPartSize(n int64) *Req {
+	r.maxPartSize = n
+	return r
+}
+
+// MultipartParts reads the parts of a multipart response body, e.g. multipart/mixed or multipart/form-data.
+// The body is streamed, each part is read into memory up to the limit set by Req.SetMaxPartSize.
+func (r *Response) MultipartParts() ([]Part, error) {
+	mediaType, params, err := mime.ParseMediaType(r.Headers().Get("Content-Type"))
+	if err != nil {
+		r.log().Errorf("Can't parse response content type Error: %v", err)
+		return nil, err
+	}
+	if !strings.HasPrefix(mediaType, "multipart/") || params["boundary"] == "" {
+		err = fmt.Errorf("response is not multipart: %s", mediaType)
+		r.log().Errorf("%v", err)
+		return nil, err
+	}
+
+	body, err := r.Reader()
+	if err != nil {
+		return nil, err
+	}
+	defer r.closeBody(body)
+
+	var parts []Part
+	reader := multipart.NewReader(body, params["boundary"])
+	for {
+		p, err := reader.NextPart()
+		if err == io.EOF {
+			return parts, nil
+		}
+		if err != nil {
+			r.log().Errorf("Can't read multipart response Error: %v", err)
+			return nil, err
+		}
+
+		var src io.Reader = p
+		if r.maxPartSize > 0 {
+			src = &limitedReader{r: p, n: r.maxPartSize, err: ErrPartTooLarge}
+		}
+		data, err := ioutil.ReadAll(src)
+		if errors.Is(err, ErrPartTooLarge) {
+			r.log().Errorf("Multipart response part %q exceeds %d bytes", p.FormName(), r.maxPartSize)
+			return nil, err
+		}
+		if err != nil {
+			r.log().Errorf("Can't read multipart response part Error: %v", err)
+			return nil, err
+		}
+
+		parts = append(parts, Part{Header: p.Header, FormName: p.FormName(), FileName: p.FileName(), Data: data})
+	}
+}
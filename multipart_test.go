@@ -0,0 +1,57 @@
+package httpreq
+
+import (
+	"context"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMultipartParts(t *testing.T) {
+
+	// Start a local HTTP server responding with a small and a large part
+	server := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			w := multipart.NewWriter(rw)
+			rw.Header().Set("Content-Type", w.FormDataContentType())
+
+			require.NoError(t, w.WriteField("name", "value"))
+			fw, err := w.CreateFormFile("file", "large.bin")
+			require.NoError(t, err)
+			_, err = fw.Write([]byte(strings.Repeat("x", 1024)))
+			require.NoError(t, err)
+			require.NoError(t, w.Close())
+		}),
+	)
+	defer server.Close()
+
+	resp, err := New(context.Background(), server.URL).Get()
+	require.NoError(t, err)
+
+	parts, err := resp.MultipartParts()
+	require.NoError(t, err)
+	require.Len(t, parts, 2)
+	require.Equal(t, "name", parts[0].FormName)
+	require.Equal(t, "value", string(parts[0].Data))
+	require.Equal(t, "large.bin", parts[1].FileName)
+	require.Len(t, parts[1].Data, 1024)
+
+	// A part at the limit is read, a larger one fails
+	resp, err = New(context.Background(), server.URL).SetMaxPartSize(1024).Get()
+	require.NoError(t, err)
+	_, err = resp.MultipartParts()
+	require.NoError(t, err)
+
+	resp, err = New(context.Background(), server.URL).SetMaxPartSize(100).Get()
+	require.NoError(t, err)
+	_, err = resp.MultipartParts()
+	require.ErrorIs(t, err, ErrPartTooLarge)
+
+	// Not multipart
+	_, err = NewResponse(&http.Response{Header: http.Header{"Content-Type": []string{"text/plain"}}}).MultipartParts()
+	require.Error(t, err)
+}
@@ -0,0 +1,284 @@
+package httpreq
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf16"
+
+	"golang.org/x/crypto/md4"
+)
+
+// NTLM message flags used by the negotiate message
+const (
+	ntlmNegotiateUnicode             = 0x00000001
+	ntlmNegotiateOEM                 = 0x00000002
+	ntlmRequestTarget                = 0x00000004
+	ntlmNegotiateNTLM                = 0x00000200
+	ntlmNegotiateAlwaysSign          = 0x00008000
+	ntlmNegotiateExtendedSessionSec  = 0x00080000
+	ntlmNegotiateFlags               = ntlmNegotiateUnicode | ntlmNegotiateOEM | ntlmRequestTarget | ntlmNegotiateNTLM | ntlmNegotiateAlwaysSign | ntlmNegotiateExtendedSessionSec
+	ntlmAvTimestamp                  = 7
+	ntlmChallengeMessageMinimumBytes = 32
+)
+
+var ntlmSignature = []byte("NTLMSSP\x00")
+
+// ntlmCredentials are the credentials set by SetNTLMAuth
+type ntlmCredentials struct {
+	username string
+	password string
+	domain   string
+}
+
+// ntlmChallenge holds the fields of a challenge message used to authenticate
+type ntlmChallenge struct {
+	flags           uint32
+	serverChallenge []byte
+	targetInfo      []byte
+}
+
+// SetNTLMAuth enables NTLM authentication. When the server responds 401 with "WWW-Authenticate: NTLM",
+// the negotiate, challenge and authenticate messages are exchanged on the same keep-alive connection
+// and the request is sent again with its body. NTLMv2 responses are used.
+func (r *Req) SetNTLMAuth(username, password, domain string) *Req {
+	r.ntlmAuth = &ntlmCredentials{username: username, password: password, domain: domain}
+	return r
+}
+
+// ntlmRetry runs the NTLM handshake after a 401 response offering NTLM and sends req again
+func (r *Req) ntlmRetry(req *http.Request, resp *http.Response) (*http.Response, error) {
+	if resp.StatusCode != http.StatusUnauthorized || !offersNTLM(resp.Header.Values("WWW-Authenticate")) {
+		return resp, nil
+	}
+
+	// The body must be sent again
+	hasBody := req.Body != nil && req.Body != http.NoBody
+	if hasBody && req.GetBody == nil {
+		r.log().Warnf("Can't authenticate with NTLM, request body can't be sent again")
+		return resp, nil
+	}
+
+	drainBody(resp.Body)
+
+	// Negotiate without a body, the connection is authenticated by the handshake
+	negotiate := req.Clone(req.Context())
+	negotiate.Body = http.NoBody
+	negotiate.GetBody = nil
+	negotiate.ContentLength = 0
+	negotiate.Header.Set("Authorization", "NTLM "+base64.StdEncoding.EncodeToString(ntlmNegotiateMessage()))
+
+	resp, err := r.client.Do(negotiate)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusUnauthorized {
+		return resp, nil
+	}
+
+	challenge, err := findNTLMChallenge(resp.Header.Values("WWW-Authenticate"))
+	if err != nil {
+		r.log().Errorf("Can't authenticate with NTLM Error: %v", err)
+		return resp, nil
+	}
+	drainBody(resp.Body)
+
+	authenticate, err := challenge.authenticateMessage(r.ntlmAuth)
+	if err != nil {
+		return nil, err
+	}
+
+	retry := req.Clone(req.Context())
+	if hasBody {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		retry.Body = body
+	}
+	retry.Header.Set("Authorization", "NTLM "+base64.StdEncoding.EncodeToString(authenticate))
+
+	return r.client.Do(retry)
+}
+
+// drainBody reads and closes a response body to reuse the connection
+func drainBody(body io.ReadCloser) {
+	_, _ = io.Copy(ioutil.Discard, body)
+	_ = body.Close()
+}
+
+// offersNTLM reports whether WWW-Authenticate header values offer NTLM
+func offersNTLM(values []string) bool {
+	for _, value := range values {
+		if strings.EqualFold(strings.TrimSpace(value), "NTLM") {
+			return true
+		}
+	}
+	return false
+}
+
+// findNTLMChallenge decodes the challenge message of WWW-Authenticate header values
+func findNTLMChallenge(values []string) (*ntlmChallenge, error) {
+	for _, value := range values {
+		fields := strings.Fields(value)
+		if len(fields) != 2 || !strings.EqualFold(fields[0], "NTLM") {
+			continue
+		}
+
+		message, err := base64.StdEncoding.DecodeString(fields[1])
+		if err != nil {
+			return nil, err
+		}
+		return parseNTLMChallenge(message)
+	}
+	return nil, errors.New("no NTLM challenge")
+}
+
+// ntlmNegotiateMessage returns the negotiate message, the first message of the handshake
+func ntlmNegotiateMessage() []byte {
+	message := make([]byte, 32)
+	copy(message, ntlmSignature)
+	binary.LittleEndian.PutUint32(message[8:], 1)
+	binary.LittleEndian.PutUint32(message[12:], ntlmNegotiateFlags)
+	return message
+}
+
+// parseNTLMChallenge parses the challenge message sent by the server
+func parseNTLMChallenge(message []byte) (*ntlmChallenge, error) {
+	if len(message) < ntlmChallengeMessageMinimumBytes || !bytes.Equal(message[:8], ntlmSignature) || binary.LittleEndian.Uint32(message[8:]) != 2 {
+		return nil, errors.New("invalid NTLM challenge message")
+	}
+
+	c := &ntlmChallenge{
+		flags:           binary.LittleEndian.Uint32(message[20:]),
+		serverChallenge: message[24:32],
+	}
+
+	// Target info is optional in old servers
+	if len(message) >= 48 {
+		length := int(binary.LittleEndian.Uint16(message[40:]))
+		offset := int(binary.LittleEndian.Uint32(message[44:]))
+		if offset+length > len(message) {
+			return nil, errors.New("invalid NTLM challenge target info")
+		}
+		c.targetInfo = message[offset : offset+length]
+	}
+
+	return c, nil
+}
+
+// authenticateMessage returns the authenticate message answering the challenge
+func (c *ntlmChallenge) authenticateMessage(creds *ntlmCredentials) ([]byte, error) {
+	clientChallenge := make([]byte, 8)
+	if _, err := rand.Read(clientChallenge); err != nil {
+		return nil, err
+	}
+
+	timestamp, ok := c.timestamp()
+	if !ok {
+		timestamp = make([]byte, 8)
+		binary.LittleEndian.PutUint64(timestamp, uint64(time.Now().UnixNano()/100+116444736000000000))
+	}
+
+	return c.authenticateMessageWith(creds, clientChallenge, timestamp), nil
+}
+
+// authenticateMessageWith returns the authenticate message for the given client challenge and timestamp
+func (c *ntlmChallenge) authenticateMessageWith(creds *ntlmCredentials, clientChallenge, timestamp []byte) []byte {
+	key := ntowfv2(creds.username, creds.password, creds.domain)
+
+	// NTLMv2 client challenge structure
+	var temp bytes.Buffer
+	temp.Write([]byte{1, 1, 0, 0, 0, 0, 0, 0})
+	temp.Write(timestamp)
+	temp.Write(clientChallenge)
+	temp.Write([]byte{0, 0, 0, 0})
+	temp.Write(c.targetInfo)
+	temp.Write([]byte{0, 0, 0, 0})
+
+	ntProof := hmacMD5(key, c.serverChallenge, temp.Bytes())
+	ntResponse := append(ntProof, temp.Bytes()...)
+	lmResponse := append(hmacMD5(key, c.serverChallenge, clientChallenge), clientChallenge...)
+
+	encode := func(s string) []byte {
+		if c.flags&ntlmNegotiateUnicode != 0 {
+			return utf16LE(s)
+		}
+		return []byte(s)
+	}
+
+	payloads := [][]byte{lmResponse, ntResponse, encode(creds.domain), encode(creds.username), nil, nil}
+
+	const headerSize = 64
+	message := make([]byte, headerSize)
+	copy(message, ntlmSignature)
+	binary.LittleEndian.PutUint32(message[8:], 3)
+
+	// Security buffers of the payloads, followed by the flags
+	offset := headerSize
+	for i, payload := range payloads {
+		field := message[12+i*8:]
+		binary.LittleEndian.PutUint16(field, uint16(len(payload)))
+		binary.LittleEndian.PutUint16(field[2:], uint16(len(payload)))
+		binary.LittleEndian.PutUint32(field[4:], uint32(offset))
+		offset += len(payload)
+	}
+	binary.LittleEndian.PutUint32(message[60:], c.flags)
+
+	for _, payload := range payloads {
+		message = append(message, payload...)
+	}
+	return message
+}
+
+// timestamp returns the server timestamp of the target info if there is one
+func (c *ntlmChallenge) timestamp() ([]byte, bool) {
+	info := c.targetInfo
+	for len(info) >= 4 {
+		id := binary.LittleEndian.Uint16(info)
+		length := int(binary.LittleEndian.Uint16(info[2:]))
+		if len(info) < 4+length {
+			break
+		}
+		if id == ntlmAvTimestamp && length == 8 {
+			return info[4:12], true
+		}
+		info = info[4+length:]
+	}
+	return nil, false
+}
+
+// ntowfv2 returns the NTLMv2 response key of the credentials
+func ntowfv2(username, password, domain string) []byte {
+	h := md4.New()
+	_, _ = h.Write(utf16LE(password))
+	return hmacMD5(h.Sum(nil), utf16LE(strings.ToUpper(username)+domain))
+}
+
+// hmacMD5 returns the HMAC-MD5 of the concatenated data
+func hmacMD5(key []byte, data ...[]byte) []byte {
+	h := hmac.New(md5.New, key)
+	for _, d := range data {
+		_, _ = h.Write(d)
+	}
+	return h.Sum(nil)
+}
+
+// utf16LE encodes s in UTF-16 little endian
+func utf16LE(s string) []byte {
+	codes := utf16.Encode([]rune(s))
+	b := make([]byte, len(codes)*2)
+	for i, code := range codes {
+		binary.LittleEndian.PutUint16(b[i*2:], code)
+	}
+	return b
+}
@@ -0,0 +1,101 @@
+package httpreq
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetNTLMAuth(t *testing.T) {
+	serverChallenge := []byte{1, 2, 3, 4, 5, 6, 7, 8}
+	var messages []int
+	var negotiateAddr string
+
+	// Target info with a timestamp and the end of list
+	targetInfo := []byte{ntlmAvTimestamp, 0, 8, 0, 1, 2, 3, 4, 5, 6, 7, 8, 0, 0, 0, 0}
+	challenge := make([]byte, 48)
+	copy(challenge, ntlmSignature)
+	binary.LittleEndian.PutUint32(challenge[8:], 2)
+	binary.LittleEndian.PutUint32(challenge[20:], ntlmNegotiateFlags)
+	copy(challenge[24:], serverChallenge)
+	binary.LittleEndian.PutUint16(challenge[40:], uint16(len(targetInfo)))
+	binary.LittleEndian.PutUint16(challenge[42:], uint16(len(targetInfo)))
+	binary.LittleEndian.PutUint32(challenge[44:], 48)
+	challenge = append(challenge, targetInfo...)
+
+	// Start a local HTTP server which requires NTLM authentication
+	server := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			body, err := ioutil.ReadAll(req.Body)
+			require.NoError(t, err)
+
+			auth := req.Header.Get("Authorization")
+			if !strings.HasPrefix(auth, "NTLM ") {
+				messages = append(messages, 0)
+				rw.Header().Set("WWW-Authenticate", "NTLM")
+				rw.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			message, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(auth, "NTLM "))
+			require.NoError(t, err)
+			require.Equal(t, ntlmSignature, message[:8])
+
+			messageType := int(binary.LittleEndian.Uint32(message[8:]))
+			messages = append(messages, messageType)
+
+			switch messageType {
+			case 1:
+				negotiateAddr = req.RemoteAddr
+				rw.Header().Set("WWW-Authenticate", "NTLM "+base64.StdEncoding.EncodeToString(challenge))
+				rw.WriteHeader(http.StatusUnauthorized)
+			case 3:
+				// The handshake authenticates the connection
+				require.Equal(t, negotiateAddr, req.RemoteAddr)
+				require.Equal(t, "body", string(body))
+
+				field := func(i int) []byte {
+					length := binary.LittleEndian.Uint16(message[12+i*8:])
+					offset := binary.LittleEndian.Uint32(message[16+i*8:])
+					return message[offset : offset+uint32(length)]
+				}
+				require.Equal(t, utf16LE("DOMAIN"), field(2))
+				require.Equal(t, utf16LE("user"), field(3))
+
+				ntResponse := field(1)
+				require.True(t, bytes.Contains(ntResponse, targetInfo))
+				proof := hmacMD5(ntowfv2("user", "pass", "DOMAIN"), serverChallenge, ntResponse[16:])
+				if !bytes.Equal(proof, ntResponse[:16]) {
+					rw.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+				_, err = rw.Write([]byte(responseData))
+				require.NoError(t, err)
+			}
+		}),
+	)
+	defer server.Close()
+
+	resp, err := New(context.Background(), server.URL).SetNTLMAuth("user", "pass", "DOMAIN").SetBody([]byte("body")).Post()
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+	require.Equal(t, []int{0, 1, 3}, messages)
+
+	body, err := resp.Body()
+	require.NoError(t, err)
+	require.Equal(t, responseData, string(body))
+}
+
+func TestNTOWFv2(t *testing.T) {
+	// Example from MS-NLMP 4.2.4.1.1
+	require.Equal(t, "0c868a403bfd7a93a3001ef22ef02e3f", hex.EncodeToString(ntowfv2("User", "Password", "Domain")))
+}
@@ -0,0 +1,29 @@
+package httpreq
+
+import (
+	"net/http"
+
+	"golang.org/x/oauth2"
+)
+
+// SetTokenSource sets the Authorization header of every request from a token of ts,
+// e.g. a Google Cloud or OAuth2 client credentials token source. Tokens are refreshed
+// as the source decides, wrap it with oauth2.ReuseTokenSource to cache them.
+func (r *Req) SetTokenSource(ts oauth2.TokenSource) *Req {
+	r.tokenSource = ts
+	return r
+}
+
+// setToken sets the Authorization header of req from the token source if set
+func (r *Req) setToken(req *http.Request) error {
+	if r.tokenSource == nil {
+		return nil
+	}
+
+	token, err := r.tokenSource.Token()
+	if err != nil {
+		return err
+	}
+	token.SetAuthHeader(req)
+	return nil
+}
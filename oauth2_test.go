@@ -0,0 +1,60 @@
+package httpreq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+// countingTokenSource returns a new token on every call
+type countingTokenSource struct {
+	calls int
+}
+
+func (s *countingTokenSource) Token() (*oauth2.Token, error) {
+	s.calls++
+	return &oauth2.Token{AccessToken: fmt.Sprintf("token%d", s.calls), TokenType: "Bearer"}, nil
+}
+
+func TestSetTokenSource(t *testing.T) {
+	var tokens []string
+
+	// Start a local HTTP server
+	server := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			tokens = append(tokens, req.Header.Get("Authorization"))
+		}),
+	)
+	defer server.Close()
+
+	r := New(context.Background(), server.URL).SetTokenSource(&countingTokenSource{})
+
+	_, err := r.Get()
+	require.NoError(t, err)
+	_, err = r.Get()
+	require.NoError(t, err)
+
+	// Refreshed for every request by this source
+	require.Equal(t, []string{"Bearer token1", "Bearer token2"}, tokens)
+
+	// Token errors fail the request
+	failing := oauth2.ReuseTokenSource(nil, tokenSourceFunc(func() (*oauth2.Token, error) {
+		return nil, errors.New("token error")
+	}))
+	_, err = New(context.Background(), server.URL).SetTokenSource(failing).Get()
+	require.Error(t, err)
+	require.Len(t, tokens, 2)
+}
+
+// tokenSourceFunc adapts a function to oauth2.TokenSource
+type tokenSourceFunc func() (*oauth2.Token, error)
+
+func (f tokenSourceFunc) Token() (*oauth2.Token, error) {
+	return f()
+}
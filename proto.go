@@ -0,0 +1,38 @@
+package httpreq
+
+import (
+	"google.golang.org/protobuf/proto"
+)
+
+// protoContentType is the media type of Protocol Buffers used by i.e. gRPC-gateway
+const protoContentType = "application/x-protobuf"
+
+// SetBodyProto sets the request body to msg marshaled as Protocol Buffers
+// and sets the Content-Type and Accept headers to application/x-protobuf
+func (r *Req) SetBodyProto(msg proto.Message) *Req {
+	data, err := proto.Marshal(msg)
+	if err != nil {
+		r.log().Errorf("Can't marshal protobuf body Error: %v", err)
+		r.setErr("SetBodyProto", err)
+		return r
+	}
+
+	r.SetContentType(protoContentType)
+	r.request.Header.Set("Accept", protoContentType)
+	return r.SetBody(data)
+}
+
+// DecodeProto unmarshals the Protocol Buffers response body into msg
+func (r *Response) DecodeProto(msg proto.Message) error {
+	body, err := r.readBody()
+	if err != nil {
+		return err
+	}
+
+	if err = proto.Unmarshal(body, msg); err != nil {
+		r.log().Errorf("Can't decode protobuf response Error: %v", err)
+		return err
+	}
+
+	return nil
+}
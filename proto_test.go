@@ -0,0 +1,49 @@
+package httpreq
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestProto(t *testing.T) {
+
+	// Start a local HTTP server which responds with the upper case value
+	server := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			require.Equal(t, "application/x-protobuf", req.Header.Get("Content-Type"))
+			require.Equal(t, "application/x-protobuf", req.Header.Get("Accept"))
+
+			body, err := ioutil.ReadAll(req.Body)
+			require.NoError(t, err)
+
+			var msg wrapperspb.StringValue
+			require.NoError(t, proto.Unmarshal(body, &msg))
+
+			body, err = proto.Marshal(wrapperspb.String(strings.ToUpper(msg.GetValue())))
+			require.NoError(t, err)
+
+			rw.Header().Set("Content-Type", "application/x-protobuf")
+			_, err = rw.Write(body)
+			require.NoError(t, err)
+		}),
+	)
+	defer server.Close()
+
+	resp, err := New(context.Background(), server.URL).SetBodyProto(wrapperspb.String("value")).Post()
+	require.NoError(t, err)
+
+	var msg wrapperspb.StringValue
+	require.NoError(t, resp.DecodeProto(&msg))
+	require.Equal(t, "VALUE", msg.GetValue())
+
+	// Invalid bodies
+	require.Error(t, NewResponse(&http.Response{Body: ioutil.NopCloser(strings.NewReader("\xff"))}).DecodeProto(&msg))
+}
@@ -0,0 +1,97 @@
+package httpreq
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// SetNoProxy sets hosts which are connected directly, bypassing the proxy set by SetProxy
+// or the environment. Entries are host names matching their subdomains too ("example.com"
+// or ".example.com"), host:port, IP addresses, CIDR ranges or "*" for all hosts, like NO_PROXY.
+func (r *Req) SetNoProxy(hosts []string) *Req {
+	transport := r.transport("SetNoProxy")
+	if transport == nil {
+		return r
+	}
+
+	r.noProxy = hosts
+	transport.Proxy = r.withNoProxy(transport.Proxy)
+	return r
+}
+
+// SetProxyFunc sets the function choosing the proxy for each request when it's sent, i.e. depending
+// on the target URL. A nil URL connects directly. Hosts set by SetNoProxy are still connected directly,
+// and it replaces the proxy set by SetProxy, so credentials of SetProxyAuth aren't used.
+func (r *Req) SetProxyFunc(proxy func(*http.Request) (*url.URL, error)) *Req {
+	transport := r.transport("SetProxyFunc")
+	if transport == nil {
+		return r
+	}
+
+	r.proxyURL = nil
+	transport.Proxy = r.withNoProxy(proxy)
+	return r
+}
+
+// withNoProxy wraps proxy so hosts set by SetNoProxy aren't proxied
+func (r *Req) withNoProxy(proxy func(*http.Request) (*url.URL, error)) func(*http.Request) (*url.URL, error) {
+	if proxy == nil {
+		return nil
+	}
+
+	return func(req *http.Request) (*url.URL, error) {
+		if bypassProxy(r.noProxy, req.URL) {
+			return nil, nil
+		}
+		return proxy(req)
+	}
+}
+
+// bypassProxy reports whether u matches a no proxy entry
+func bypassProxy(noProxy []string, u *url.URL) bool {
+	hostname := strings.ToLower(u.Hostname())
+	port := u.Port()
+	if port == "" {
+		port = map[string]string{"http": "80", "https": "443"}[u.Scheme]
+	}
+	ip := net.ParseIP(hostname)
+
+	for _, entry := range noProxy {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry == "" {
+			continue
+		}
+		if entry == "*" {
+			return true
+		}
+
+		if _, ipNet, err := net.ParseCIDR(entry); err == nil {
+			if ip != nil && ipNet.Contains(ip) {
+				return true
+			}
+			continue
+		}
+
+		// Entries with a port match only that port
+		if h, p, err := net.SplitHostPort(entry); err == nil {
+			if p != port {
+				continue
+			}
+			entry = h
+		}
+
+		entry = strings.TrimPrefix(strings.TrimPrefix(entry, "*"), ".")
+		if ip != nil {
+			if entryIP := net.ParseIP(strings.Trim(entry, "[]")); entryIP != nil && entryIP.Equal(ip) {
+				return true
+			}
+			continue
+		}
+		if hostname == entry || strings.HasSuffix(hostname, "."+entry) {
+			return true
+		}
+	}
+	return false
+}
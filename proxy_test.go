@@ -0,0 +1,215 @@
+package httpreq
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetNoProxy(t *testing.T) {
+	var proxied int32
+
+	// Start a local HTTP server acting as a proxy
+	proxy := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			atomic.AddInt32(&proxied, 1)
+			_, _ = rw.Write([]byte("proxied"))
+		}),
+	)
+	defer proxy.Close()
+
+	// Start a local HTTP server
+	server := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			_, _ = rw.Write([]byte("direct"))
+		}),
+	)
+	defer server.Close()
+
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	// Without the bypass the server is reached through the proxy
+	resp, err := New(context.Background(), server.URL).SetProxy(proxy.URL).Get()
+	require.NoError(t, err)
+	body, err := resp.Body()
+	require.NoError(t, err)
+	require.Equal(t, "proxied", string(body))
+
+	// Before and after SetProxy
+	resp, err = New(context.Background(), server.URL).SetProxy(proxy.URL).SetNoProxy([]string{serverURL.Host}).Get()
+	require.NoError(t, err)
+	body, err = resp.Body()
+	require.NoError(t, err)
+	require.Equal(t, "direct", string(body))
+
+	resp, err = New(context.Background(), server.URL).SetNoProxy([]string{"127.0.0.0/8"}).SetProxy(proxy.URL).Get()
+	require.NoError(t, err)
+	body, err = resp.Body()
+	require.NoError(t, err)
+	require.Equal(t, "direct", string(body))
+
+	require.Equal(t, int32(1), atomic.LoadInt32(&proxied))
+}
+
+func TestSetProxyFunc(t *testing.T) {
+
+	// Start a local HTTP server acting as a proxy
+	proxy := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			_, _ = rw.Write([]byte("proxied"))
+		}),
+	)
+	defer proxy.Close()
+
+	// Start local HTTP servers
+	handler := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		_, _ = rw.Write([]byte("direct"))
+	})
+	internal := httptest.NewServer(handler)
+	defer internal.Close()
+	external := httptest.NewServer(handler)
+	defer external.Close()
+
+	proxyURL, err := url.Parse(proxy.URL)
+	require.NoError(t, err)
+	externalURL, err := url.Parse(external.URL)
+	require.NoError(t, err)
+
+	// Only the external host is proxied
+	proxyFunc := func(req *http.Request) (*url.URL, error) {
+		if req.URL.Host == externalURL.Host {
+			return proxyURL, nil
+		}
+		return nil, nil
+	}
+
+	for target, expected := range map[string]string{internal.URL: "direct", external.URL: "proxied"} {
+		resp, err := New(context.Background(), target).SetProxyFunc(proxyFunc).Get()
+		require.NoError(t, err)
+		body, err := resp.Body()
+		require.NoError(t, err)
+		require.Equal(t, expected, string(body), target)
+	}
+
+	// The proxy function replaces SetProxy
+	resp, err := New(context.Background(), internal.URL).SetProxy(proxy.URL).SetProxyFunc(proxyFunc).Get()
+	require.NoError(t, err)
+	body, err := resp.Body()
+	require.NoError(t, err)
+	require.Equal(t, "direct", string(body))
+}
+
+func TestSetProxyHTTPS(t *testing.T) {
+	var proxied, tunneled int32
+
+	// Start a local HTTPS server acting as a proxy
+	proxy := httptest.NewTLSServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			if req.Method != http.MethodConnect {
+				atomic.AddInt32(&proxied, 1)
+				resp, err := http.DefaultTransport.RoundTrip(req)
+				if err != nil {
+					rw.WriteHeader(http.StatusBadGateway)
+					return
+				}
+				defer resp.Body.Close()
+				rw.WriteHeader(resp.StatusCode)
+				_, _ = io.Copy(rw, resp.Body)
+				return
+			}
+
+			atomic.AddInt32(&tunneled, 1)
+			target, err := net.Dial("tcp", req.Host)
+			if err != nil {
+				rw.WriteHeader(http.StatusBadGateway)
+				return
+			}
+			defer target.Close()
+
+			conn, _, err := rw.(http.Hijacker).Hijack()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+
+			_, _ = conn.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n"))
+			go func() {
+				_, _ = io.Copy(target, conn)
+			}()
+			_, _ = io.Copy(conn, target)
+		}),
+	)
+	defer proxy.Close()
+
+	// Start a local HTTP and HTTPS server
+	handler := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		_, _ = rw.Write([]byte(responseData))
+	})
+	server := httptest.NewServer(handler)
+	defer server.Close()
+	tlsServer := httptest.NewTLSServer(handler)
+	defer tlsServer.Close()
+
+	// Test servers share the certificate
+	pool := x509.NewCertPool()
+	pool.AddCert(proxy.Certificate())
+
+	for _, target := range []string{server.URL, tlsServer.URL} {
+		resp, err := New(context.Background(), target).
+			SetTLSConfig(&tls.Config{RootCAs: pool}).
+			SetProxy(proxy.URL).
+			Get()
+		require.NoError(t, err)
+		body, err := resp.Body()
+		require.NoError(t, err)
+		require.Equal(t, responseData, string(body))
+	}
+	require.Equal(t, int32(1), atomic.LoadInt32(&proxied))
+	require.Equal(t, int32(1), atomic.LoadInt32(&tunneled))
+
+	// The proxy certificate is verified
+	_, err := New(context.Background(), server.URL).SetProxy(proxy.URL).Get()
+	require.Error(t, err)
+
+	require.Error(t, New(context.Background(), server.URL).SetProxy("ftp://proxy.com:21").err)
+	require.Error(t, New(context.Background(), server.URL).SetProxy("proxy.com:8080").err)
+}
+
+func TestBypassProxy(t *testing.T) {
+	tests := []struct {
+		url      string
+		noProxy  []string
+		expected bool
+	}{
+		{"http://example.com/", []string{"example.com"}, true},
+		{"http://api.example.com/", []string{"example.com"}, true},
+		{"http://api.example.com/", []string{".example.com"}, true},
+		{"http://api.example.com/", []string{"*.example.com"}, true},
+		{"http://badexample.com/", []string{"example.com"}, false},
+		{"http://EXAMPLE.com/", []string{"Example.COM"}, true},
+		{"http://example.com:8080/", []string{"example.com:8080"}, true},
+		{"http://example.com/", []string{"example.com:8080"}, false},
+		{"https://example.com/", []string{"example.com:443"}, true},
+		{"http://10.1.2.3/", []string{"10.0.0.0/8"}, true},
+		{"http://192.168.1.1/", []string{"10.0.0.0/8"}, false},
+		{"http://[::1]:80/", []string{"::1"}, true},
+		{"http://anything/", []string{"*"}, true},
+		{"http://example.com/", nil, false},
+	}
+
+	for _, test := range tests {
+		u, err := url.Parse(test.url)
+		require.NoError(t, err)
+		require.Equal(t, test.expected, bypassProxy(test.noProxy, u), test.url)
+	}
+}
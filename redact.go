@@ -0,0 +1,105 @@
+package httpreq
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const redacted = "REDACTED"
+
+var (
+	// authRegexp matches credentials of Authorization and Proxy-Authorization header values,
+	// also as printed in http.Header maps, keeping the scheme
+	authRegexp = regexp.MustCompile(`(?i)(\b(?:proxy-)?authorization["']?\s*[:=]\s*\[?["']?(?:(?:bearer|basic|digest|token|negotiate|ntlm)\s+)?)[^\s,;"'\]]+`)
+
+	// queryTokenRegexp matches values of query parameters commonly holding secrets
+	queryTokenRegexp = regexp.MustCompile(`(?i)([?&](?:access_token|refresh_token|id_token|token|api_key|apikey|key|secret|client_secret|password|passwd|signature|sig)=)[^&#\s"']*`)
+
+	// userinfoRegexp matches the password in URL userinfo
+	userinfoRegexp = regexp.MustCompile(`(://[^:/\s@]+:)[^@/\s]+@`)
+)
+
+// redactSecrets replaces Authorization credentials, secret query parameters
+// and URL passwords in s with REDACTED
+func redactSecrets(s string) string {
+	s = authRegexp.ReplaceAllString(s, "${1}"+redacted)
+	s = queryTokenRegexp.ReplaceAllString(s, "${1}"+redacted)
+	s = userinfoRegexp.ReplaceAllString(s, "${1}"+redacted+"@")
+	return s
+}
+
+// SetRedactor sets a function applied to every message logged for this request, i.e. to remove
+// secrets from URLs and headers. It's applied after the built-in redaction of Authorization
+// credentials, common token query parameters and URL passwords.
+func (r *Req) SetRedactor(redactor func(string) string) *Req {
+	r.redactor = redactor
+	return r
+}
+
+// redactingLogger formats messages and redacts them before passing to the wrapped logger
+type redactingLogger struct {
+	logger   Logger
+	redactor func(string) string
+}
+
+// newRedactingLogger returns a logger redacting secrets and applying redactor if it's not nil
+func newRedactingLogger(l Logger, redactor func(string) string) Logger {
+	return &redactingLogger{logger: l, redactor: redactor}
+}
+
+func (l *redactingLogger) redact(s string) string {
+	s = redactSecrets(s)
+	if l.redactor != nil {
+		s = l.redactor(s)
+	}
+	return s
+}
+
+func (l *redactingLogger) sprint(args ...interface{}) string {
+	return l.redact(strings.TrimSuffix(fmt.Sprintln(args...), "\n"))
+}
+
+func (l *redactingLogger) sprintf(format string, args ...interface{}) string {
+	return l.redact(fmt.Sprintf(format, args...))
+}
+
+func (l *redactingLogger) Debug(args ...interface{}) {
+	l.logger.Debug(l.sprint(args...))
+}
+
+func (l *redactingLogger) Debugf(format string, args ...interface{}) {
+	l.logger.Debugf("%s", l.sprintf(format, args...))
+}
+
+func (l *redactingLogger) Info(args ...interface{}) {
+	l.logger.Info(l.sprint(args...))
+}
+
+func (l *redactingLogger) Infof(format string, args ...interface{}) {
+	l.logger.Infof("%s", l.sprintf(format, args...))
+}
+
+func (l *redactingLogger) Warn(args ...interface{}) {
+	l.logger.Warn(l.sprint(args...))
+}
+
+func (l *redactingLogger) Warnf(format string, args ...interface{}) {
+	l.logger.Warnf("%s", l.sprintf(format, args...))
+}
+
+func (l *redactingLogger) Error(args ...interface{}) {
+	l.logger.Error(l.sprint(args...))
+}
+
+func (l *redactingLogger) Errorf(format string, args ...interface{}) {
+	l.logger.Errorf("%s", l.sprintf(format, args...))
+}
+
+func (l *redactingLogger) Fatal(args ...interface{}) {
+	l.logger.Fatal(l.sprint(args...))
+}
+
+func (l *redactingLogger) Fatalf(format string, args ...interface{}) {
+	l.logger.Fatalf("%s", l.sprintf(format, args...))
+}
@@ -0,0 +1,61 @@
+package httpreq
+
+import (
+	"net/http"
+)
+
+// SetLocationHeaders sets the headers FollowLocationOnce sends to the redirect location,
+// other headers like Authorization aren't sent to it
+func (r *Req) SetLocationHeaders(names ...string) *Req {
+	r.locationHeaders = names
+	return r
+}
+
+// FollowLocationOnce is a get http request which doesn't follow redirects automatically.
+// If the response is a redirect, a fresh get request is sent to its Location with only the headers
+// set by SetLocationHeaders, e.g. for APIs redirecting once to a signed object storage URL.
+// Other responses are returned as is.
+func (r *Req) FollowLocationOnce() (*Response, error) {
+	return r.exclusive(r.followLocationOnce)
+}
+
+// followLocationOnce sends the request of FollowLocationOnce
+func (r *Req) followLocationOnce() (*Response, error) {
+	req, err := r.prepare(http.MethodGet)
+	if err != nil {
+		return nil, err
+	}
+
+	// Send the first request with a copy of the client not following redirects
+	client := r.client
+	noRedirect := *client
+	noRedirect.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	r.client = &noRedirect
+	resp, err := r.sendUnchecked(req)
+	r.client = client
+	if err != nil {
+		return resp, err
+	}
+
+	// Only the final response is checked (ExpectContentType...), not the redirect
+	location, err := resp.Response().Location()
+	if err != nil || resp.StatusCode() < 300 || resp.StatusCode() >= 400 {
+		return r.checked(req, resp)
+	}
+	drainBody(resp.Response().Body)
+
+	next, err := http.NewRequestWithContext(req.Context(), http.MethodGet, location.String(), nil)
+	if err != nil {
+		r.log().Errorf("Error creating redirect request: %s, %v", location, err)
+		return nil, err
+	}
+	for _, name := range r.locationHeaders {
+		if values := req.Header.Values(name); len(values) > 0 {
+			next.Header[http.CanonicalHeaderKey(name)] = values
+		}
+	}
+
+	return r.sendRequest(next)
+}
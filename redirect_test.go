@@ -0,0 +1,73 @@
+package httpreq
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFollowLocationOnce(t *testing.T) {
+
+	// Start a local object storage server which rejects unexpected credentials
+	storage := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			if req.Header.Get("Authorization") != "" {
+				rw.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			require.Equal(t, "id", req.Header.Get("X-Request-Id"))
+			require.Equal(t, "signature", req.URL.Query().Get("sig"))
+
+			_, err := rw.Write([]byte(responseData))
+			require.NoError(t, err)
+		}),
+	)
+	defer storage.Close()
+
+	// Start a local API server which redirects to the storage once authorized
+	api := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			if req.Header.Get("Authorization") != "Bearer token" {
+				rw.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			http.Redirect(rw, req, storage.URL+"/object?sig=signature", http.StatusFound)
+		}),
+	)
+	defer api.Close()
+
+	headers := map[string]string{"Authorization": "Bearer token", "X-Request-ID": "id"}
+
+	// Following automatically sends the credentials to the storage
+	resp, err := New(context.Background(), api.URL).SetHeaders(headers).Get()
+	require.NoError(t, err)
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode())
+
+	resp, err = New(context.Background(), api.URL).SetHeaders(headers).SetLocationHeaders("x-request-id").FollowLocationOnce()
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+
+	body, err := resp.Body()
+	require.NoError(t, err)
+	require.Equal(t, responseData, string(body))
+
+	// Responses other than redirects are returned as is
+	resp, err = New(context.Background(), api.URL).FollowLocationOnce()
+	require.NoError(t, err)
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode())
+
+	// Only the final response is checked
+	resp, err = New(context.Background(), api.URL).SetHeaders(headers).SetLocationHeaders("x-request-id").ExpectContentType("text/plain").FollowLocationOnce()
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+
+	_, err = New(context.Background(), api.URL).SetHeaders(headers).SetLocationHeaders("x-request-id").ExpectContentType("application/json").FollowLocationOnce()
+	require.ErrorIs(t, err, ErrUnexpectedContentType)
+
+	resp, err = New(context.Background(), api.URL).FailOnError().FollowLocationOnce()
+	require.Error(t, err)
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode())
+}
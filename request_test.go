@@ -1,15 +1,27 @@
 package httpreq
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
 	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"testing/iotest"
 	"time"
 
 	"github.com/stretchr/testify/require"
@@ -195,6 +207,70 @@ func TestPostJSON(t *testing.T) {
 	require.Equal(t, string(sonuc), responseData)
 }
 
+func TestStrictJSON(t *testing.T) {
+
+	// Start a local HTTP server responding with the content type in the path
+	server := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			if req.URL.Path == "/empty" {
+				rw.WriteHeader(http.StatusNoContent)
+				return
+			}
+			rw.Header().Set("Content-Type", req.URL.Query().Get("type"))
+			_, _ = rw.Write([]byte(responseData))
+		}),
+	)
+	defer server.Close()
+
+	for _, contentType := range []string{"application/json", "application/json; charset=utf-8", "application/problem+json"} {
+		resp, err := New(context.Background(), server.URL).SetParam("type", contentType).StrictJSON().PostJSON()
+		require.NoError(t, err, contentType)
+		require.Equal(t, http.StatusOK, resp.StatusCode())
+	}
+
+	resp, err := New(context.Background(), server.URL+"/empty").StrictJSON().PostJSON()
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode())
+
+	// HTML error pages fail with the response
+	resp, err = New(context.Background(), server.URL).SetParam("type", "text/html").StrictJSON().PostJSON()
+	require.ErrorIs(t, err, ErrUnexpectedContentType)
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+
+	// Not strict by default
+	_, err = New(context.Background(), server.URL).SetParam("type", "text/html").PostJSON()
+	require.NoError(t, err)
+}
+
+func TestExpectContentType(t *testing.T) {
+
+	// Start a local HTTP server responding with the content type in the query
+	server := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			rw.Header().Set("Content-Type", req.URL.Query().Get("type"))
+			_, _ = rw.Write([]byte(responseData))
+		}),
+	)
+	defer server.Close()
+
+	resp, err := New(context.Background(), server.URL).
+		SetParam("type", "application/XML; charset=utf-8").
+		ExpectContentType("application/xml").
+		Get()
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+
+	// Mismatches fail with the response
+	resp, err = New(context.Background(), server.URL).SetParam("type", "text/html").ExpectContentType("application/xml").Get()
+	require.ErrorIs(t, err, ErrUnexpectedContentType)
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+
+	_, err = New(context.Background(), server.URL).ExpectContentType("application/xml").Get()
+	require.ErrorIs(t, err, ErrUnexpectedContentType)
+
+	require.Error(t, New(context.Background(), server.URL).ExpectContentType("invalid/").err)
+}
+
 func TestSetFormEarlyError(t *testing.T) {
 	r := &Req{err: fmt.Errorf("Test Error")}
 	files := []map[string]string{}
@@ -251,6 +327,48 @@ func TestSendEarlyError(t *testing.T) {
 	require.Nil(t, resp)
 }
 
+func TestConcurrentUse(t *testing.T) {
+	received := make(chan struct{})
+	release := make(chan struct{})
+
+	// Start a local HTTP server which blocks the first request until it's released
+	server := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			if req.URL.Query().Get("block") != "" {
+				close(received)
+				<-release
+			}
+		}),
+	)
+	defer server.Close()
+
+	r := New(context.Background(), server.URL).SetParam("block", "1")
+
+	done := make(chan error)
+	go func() {
+		_, err := r.Get()
+		done <- err
+	}()
+
+	<-received
+	_, err := r.Get()
+	require.ErrorIs(t, err, ErrConcurrentUse)
+
+	// Redirect and meta refresh following are guarded too
+	_, err = r.FollowLocationOnce()
+	require.ErrorIs(t, err, ErrConcurrentUse)
+	_, err = r.FollowMetaRefresh(1)
+	require.ErrorIs(t, err, ErrConcurrentUse)
+
+	close(release)
+	require.NoError(t, <-done)
+
+	// Sequential sends are fine
+	r.Params.Del("block")
+	_, err = r.Get()
+	require.NoError(t, err)
+}
+
 func TestSendGenerateURLError(t *testing.T) {
 	// % should causes error at url.Parse
 	r := New(context.Background(), "%")
@@ -272,6 +390,51 @@ func TestGenerateURL(t *testing.T) {
 	url, err := generateURL("%")
 	require.Error(t, err)
 	require.Nil(t, url)
+
+	var table = []struct {
+		address  string
+		expected string
+	}{
+		{"HTTP://Example.COM/Path?Key=Value", "http://example.com/Path?Key=Value"},
+		{"http://[::1]:8080/Path", "http://[::1]:8080/Path"},
+		{"http://[FE80::AB]/", "http://[fe80::ab]/"},
+		{"http://[fe80::1%25eth0]/", "http://[fe80::1%25eth0]/"},
+		{"http://[FE80::1%25Eth0]:8080/Path", "http://[fe80::1%25Eth0]:8080/Path"},
+	}
+
+	for _, row := range table {
+		url, err := generateURL(row.address)
+		require.NoError(t, err)
+		require.Equal(t, row.expected, url.String())
+	}
+}
+
+func TestIPv6Request(t *testing.T) {
+	listener, err := net.Listen("tcp6", "[::1]:0")
+	if err != nil {
+		t.Skipf("IPv6 is not available: %v", err)
+	}
+
+	// Start a local HTTP server on IPv6 loopback
+	server := httptest.NewUnstartedServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			require.Equal(t, "/Mixed/Case", req.URL.Path)
+			_, err := rw.Write([]byte(responseData))
+			require.NoError(t, err)
+		}),
+	)
+	server.Listener = listener
+	server.Start()
+	defer server.Close()
+
+	port := listener.Addr().(*net.TCPAddr).Port
+
+	resp, err := New(context.Background(), fmt.Sprintf("http://[::1]:%d/Mixed/Case", port)).Get()
+	require.NoError(t, err)
+
+	body, err := resp.Body()
+	require.NoError(t, err)
+	require.Equal(t, responseData, string(body))
 }
 
 func TestSetBodyXML(t *testing.T) {
@@ -280,6 +443,60 @@ func TestSetBodyXML(t *testing.T) {
 	require.Equal(t, "application/xml; charset=UTF-8", r.request.Header.Get("Content-Type"))
 }
 
+func TestSetAPIVersion(t *testing.T) {
+
+	// Start a local HTTP server
+	server := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			_, _ = rw.Write([]byte(req.Header.Get("Accept-Version") + "," + req.Header.Get("X-API-Version")))
+		}),
+	)
+	defer server.Close()
+
+	resp, err := New(context.Background(), server.URL).SetAPIVersion("", "1.2").Get()
+	require.NoError(t, err)
+	body, err := resp.Body()
+	require.NoError(t, err)
+	require.Equal(t, "1.2,", string(body))
+
+	resp, err = New(context.Background(), server.URL).SetAPIVersion("X-API-Version", "2").Get()
+	require.NoError(t, err)
+	body, err = resp.Body()
+	require.NoError(t, err)
+	require.Equal(t, ",2", string(body))
+}
+
+func TestSetHeadersFrom(t *testing.T) {
+
+	// Start a local HTTP server
+	server := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			require.Equal(t, []string{"a", "b"}, req.Header.Values("X-Forwarded-For"))
+			require.Equal(t, []string{"value"}, req.Header.Values("X-Replaced"))
+			require.Equal(t, "kept", req.Header.Get("X-Kept"))
+		}),
+	)
+	defer server.Close()
+
+	incoming := http.Header{}
+	incoming.Add("X-Forwarded-For", "a")
+	incoming.Add("X-Forwarded-For", "b")
+	incoming.Set("X-Replaced", "value")
+
+	resp, err := New(context.Background(), server.URL).
+		SetHeaders(map[string]string{"X-Replaced": "old", "X-Kept": "kept"}).
+		SetHeadersFrom(incoming).
+		Get()
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+
+	// The headers aren't shared
+	incoming.Add("X-Forwarded-For", "c")
+	r := New(context.Background(), server.URL).SetHeadersFrom(incoming)
+	incoming.Del("X-Forwarded-For")
+	require.Equal(t, []string{"a", "b", "c"}, r.request.Header.Values("X-Forwarded-For"))
+}
+
 func TestSetCookie(t *testing.T) {
 	r := New(context.Background(), "")
 
@@ -315,6 +532,29 @@ func TestSetParam(t *testing.T) {
 	r := New(context.Background(), "")
 	r.SetParam("test", "test")
 	require.Equal(t, "test", r.Params.Get("test"))
+
+	// Params replace values in the address query
+	r = New(context.Background(), "http://example.com/path?a=1&c=4")
+	r.SetParam("a", "2").SetParams(map[string]string{"b": "3"})
+
+	req, err := r.Request()
+	require.NoError(t, err)
+	require.Equal(t, url.Values{"a": {"2"}, "b": {"3"}, "c": {"4"}}, req.URL.Query())
+}
+
+func TestMergeQuery(t *testing.T) {
+	r := New(context.Background(), "http://example.com/path?a=1")
+	r.MergeQuery(url.Values{"a": {"2"}, "b": {"3"}})
+	require.NoError(t, r.err)
+
+	req, err := r.Request()
+	require.NoError(t, err)
+	require.Equal(t, url.Values{"a": {"1", "2"}, "b": {"3"}}, req.URL.Query())
+	require.Equal(t, "/path", req.URL.Path)
+
+	// % should causes error at url.Parse
+	r = New(context.Background(), "%").MergeQuery(url.Values{"a": {"1"}})
+	require.Error(t, r.err)
 }
 
 func TestNewWithBackgroundCtx(t *testing.T) {
@@ -340,3 +580,1113 @@ func TestCancelCtx(t *testing.T) {
 	require.Equal(t, context.Canceled, r.request.Context().Err())
 
 }
+
+func TestSetChunked(t *testing.T) {
+	body := []byte("chunked request body")
+
+	// Start a local HTTP server
+	server := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			require.Equal(t, []string{"chunked"}, req.TransferEncoding)
+			require.Equal(t, int64(-1), req.ContentLength)
+
+			data, err := ioutil.ReadAll(req.Body)
+			require.NoError(t, err)
+			require.Equal(t, body, data)
+		}),
+	)
+	defer server.Close()
+
+	resp, err := New(context.Background(), server.URL).SetBody(body).SetChunked().Post()
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+	require.NoError(t, resp.Close())
+}
+
+func TestSetMaxResponseHeaderBytes(t *testing.T) {
+
+	// Start a local HTTP server
+	server := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			for i := 0; i < 100; i++ {
+				rw.Header().Set(fmt.Sprintf("X-Header-%d", i), strings.Repeat("a", 100))
+			}
+		}),
+	)
+	defer server.Close()
+
+	_, err := New(context.Background(), server.URL).SetMaxResponseHeaderBytes(1024).Get()
+	require.Error(t, err)
+	require.True(t, errors.Is(err, ErrHeadersTooLarge))
+
+	resp, err := New(context.Background(), server.URL).Get()
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+}
+
+func TestSetTimeouts(t *testing.T) {
+	r := New(context.Background(), "")
+	r.SetTimeouts(Timeouts{
+		Dial:           1 * time.Second,
+		TLSHandshake:   2 * time.Second,
+		ResponseHeader: 3 * time.Second,
+		Total:          4 * time.Second,
+	})
+
+	transport := r.client.Transport.(*http.Transport)
+	require.Equal(t, 1*time.Second, r.dialer.Timeout)
+	require.Equal(t, 2*time.Second, transport.TLSHandshakeTimeout)
+	require.Equal(t, 3*time.Second, transport.ResponseHeaderTimeout)
+	require.Equal(t, 4*time.Second, r.client.Timeout)
+
+	// Zero values leave the settings unchanged
+	r.SetTimeouts(Timeouts{Total: 5 * time.Second})
+	require.Equal(t, 1*time.Second, r.dialer.Timeout)
+	require.Equal(t, 2*time.Second, transport.TLSHandshakeTimeout)
+	require.Equal(t, 3*time.Second, transport.ResponseHeaderTimeout)
+	require.Equal(t, 5*time.Second, r.client.Timeout)
+}
+
+func TestSetTimeoutsResponseHeader(t *testing.T) {
+
+	// Start a local HTTP server
+	server := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			time.Sleep(200 * time.Millisecond)
+		}),
+	)
+	defer server.Close()
+
+	_, err := New(context.Background(), server.URL).
+		SetTimeouts(Timeouts{ResponseHeader: 50 * time.Millisecond}).
+		Get()
+	require.Error(t, err)
+}
+
+func TestDisableTimeout(t *testing.T) {
+
+	// Start a local HTTP server streaming events longer than the timeout
+	server := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			rw.Header().Set("Content-Type", "text/event-stream")
+			for i := 0; i < 5; i++ {
+				// The client which timed out is gone
+				if _, err := fmt.Fprintf(rw, "data: %d\n\n", i); err != nil {
+					return
+				}
+				rw.(http.Flusher).Flush()
+				time.Sleep(50 * time.Millisecond)
+			}
+		}),
+	)
+	defer server.Close()
+
+	// The stream exceeds the timeout
+	resp, err := New(context.Background(), server.URL).SetTimeout(100 * time.Millisecond).Get()
+	require.NoError(t, err)
+	_, err = resp.Body()
+	require.Error(t, err)
+
+	r := New(context.Background(), server.URL).SetTimeout(100 * time.Millisecond).DisableTimeout()
+	require.Zero(t, r.client.Timeout)
+
+	resp, err = r.Get()
+	require.NoError(t, err)
+	body, err := resp.Body()
+	require.NoError(t, err)
+	require.Equal(t, 5, strings.Count(string(body), "data: "))
+}
+
+func TestSetMaxResponseHeaderCount(t *testing.T) {
+
+	// Start a local HTTP server emitting hundreds of headers
+	server := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			for i := 0; i < 500; i++ {
+				rw.Header().Add(fmt.Sprintf("X-Header-%d", i%100), "value")
+			}
+		}),
+	)
+	defer server.Close()
+
+	_, err := New(context.Background(), server.URL).SetMaxResponseHeaderCount(100).Get()
+	require.ErrorIs(t, err, ErrTooManyHeaders)
+
+	resp, err := New(context.Background(), server.URL).SetMaxResponseHeaderCount(1000).Get()
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, resp.HeaderCount(), 500)
+}
+
+func TestSetConnectTimeout(t *testing.T) {
+	r := New(context.Background(), "http://10.255.255.1/").SetConnectTimeout(200 * time.Millisecond)
+	require.Equal(t, 200*time.Millisecond, r.dialer.Timeout)
+	require.Equal(t, 30*time.Second, r.client.Timeout)
+
+	// Dials hanging until they're canceled fail at the timeout, also with a transport of the caller
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		},
+	}
+
+	start := time.Now()
+	_, err := New(context.Background(), "http://example.com/").
+		SetTransport(transport).
+		SetConnectTimeout(100 * time.Millisecond).
+		Get()
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Less(t, int64(time.Since(start)), int64(2*time.Second))
+}
+
+func TestSetDialContext(t *testing.T) {
+
+	// Start a local HTTPS server
+	server := httptest.NewTLSServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			_, _ = rw.Write([]byte(responseData))
+		}),
+	)
+	defer server.Close()
+
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	// The test certificate is valid for example.com, which is dialed at the local server
+	var dialed []string
+	dial := func(ctx context.Context, network, addr string) (net.Conn, error) {
+		dialed = append(dialed, addr)
+		return (&net.Dialer{}).DialContext(ctx, network, serverURL.Host)
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(server.Certificate())
+
+	resp, err := New(context.Background(), "https://example.com:"+serverURL.Port()).
+		CountBytes().
+		SetDialContext(dial).
+		SetTLSConfig(&tls.Config{RootCAs: pool}).
+		Get()
+	require.NoError(t, err)
+
+	body, err := resp.Body()
+	require.NoError(t, err)
+	require.Equal(t, responseData, string(body))
+	require.Equal(t, []string{"example.com:" + serverURL.Port()}, dialed)
+	require.Positive(t, resp.BytesRead())
+
+	r := New(context.Background(), server.URL).SetClient(&http.Client{}).SetDialContext(dial)
+	require.Error(t, r.err)
+
+	// A shared client isn't changed, other requests dial as before
+	transport := &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}}
+	shared := &http.Client{Transport: transport}
+
+	dialed = nil
+	_, err = New(context.Background(), "https://example.com:"+serverURL.Port()).SetClient(shared).SetDialContext(dial).Get()
+	require.NoError(t, err)
+	require.Len(t, dialed, 1)
+	require.Nil(t, transport.DialContext)
+	require.Equal(t, transport, shared.Transport)
+
+	_, err = New(context.Background(), server.URL).SetClient(shared).Get()
+	require.NoError(t, err)
+	require.Len(t, dialed, 1)
+
+	// Transports of the caller dial with it too, whatever the order of the setters
+	dialed = nil
+	resp, err = New(context.Background(), "https://example.com:"+serverURL.Port()).
+		SetTransport(&http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}}).
+		CountBytes().
+		SetDialContext(dial).
+		Get()
+	require.NoError(t, err)
+
+	body, err = resp.Body()
+	require.NoError(t, err)
+	require.Equal(t, responseData, string(body))
+	require.Equal(t, []string{"example.com:" + serverURL.Port()}, dialed)
+	require.Positive(t, resp.BytesRead())
+}
+
+func TestSetSocketReadDeadline(t *testing.T) {
+	done := make(chan struct{})
+
+	// Start a local HTTP server which stops sending in the middle of the body
+	server := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			rw.Header().Set("Content-Length", "100")
+			_, _ = rw.Write([]byte("partial"))
+			rw.(http.Flusher).Flush()
+
+			if req.URL.Path == "/slow" {
+				for i := 0; i < 3; i++ {
+					time.Sleep(50 * time.Millisecond)
+					_, _ = rw.Write([]byte(strings.Repeat("x", 31)))
+					rw.(http.Flusher).Flush()
+				}
+				return
+			}
+
+			select {
+			case <-req.Context().Done():
+			case <-done:
+			}
+		}),
+	)
+	defer server.Close()
+	defer close(done)
+
+	start := time.Now()
+	resp, err := New(context.Background(), server.URL).SetSocketReadDeadline(100 * time.Millisecond).Get()
+	require.NoError(t, err)
+
+	_, err = resp.Body()
+	var netErr net.Error
+	require.True(t, errors.As(err, &netErr))
+	require.True(t, netErr.Timeout())
+	require.Less(t, int64(time.Since(start)), int64(2*time.Second))
+	require.NoError(t, resp.Close())
+
+	// Slow transfers receiving data in time don't fail
+	resp, err = New(context.Background(), server.URL+"/slow").SetSocketReadDeadline(100 * time.Millisecond).Get()
+	require.NoError(t, err)
+
+	body, err := resp.Body()
+	require.NoError(t, err)
+	require.Len(t, body, 100)
+}
+
+func TestGetBodyWarning(t *testing.T) {
+	l := &captureLogger{}
+	setTestLogger(t, l)
+
+	// Start a local HTTP server
+	server := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {}),
+	)
+	defer server.Close()
+
+	_, err := New(context.Background(), server.URL).SetBody([]byte("body")).Get()
+	require.NoError(t, err)
+	require.Contains(t, l.all(), "Sending GET request with a body")
+}
+
+func TestDisallowGetBody(t *testing.T) {
+
+	// Start a local HTTP server
+	server := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {}),
+	)
+	defer server.Close()
+
+	resp, err := New(context.Background(), server.URL).DisallowGetBody().SetBody([]byte("body")).Get()
+	require.Equal(t, ErrGetBody, err)
+	require.Nil(t, resp)
+
+	// Other methods are not affected
+	_, err = New(context.Background(), server.URL).DisallowGetBody().SetBody([]byte("body")).Post()
+	require.NoError(t, err)
+}
+
+func TestSetDeadline(t *testing.T) {
+
+	// Start a local HTTP server which stalls in the middle of the body
+	server := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			_, err := rw.Write([]byte("partial"))
+			require.NoError(t, err)
+			rw.(http.Flusher).Flush()
+
+			select {
+			case <-req.Context().Done():
+			case <-time.After(2 * time.Second):
+			}
+		}),
+	)
+	defer server.Close()
+
+	// Already past deadline fails before sending
+	_, err := New(context.Background(), server.URL).SetDeadline(time.Now().Add(-time.Second)).Get()
+	require.Error(t, err)
+	require.True(t, errors.Is(err, context.DeadlineExceeded))
+
+	// Deadline is reached while reading the body
+	resp, err := New(context.Background(), server.URL).SetDeadline(time.Now().Add(200 * time.Millisecond)).Get()
+	require.NoError(t, err)
+
+	start := time.Now()
+	_, err = resp.Body()
+	require.Error(t, err)
+	require.True(t, errors.Is(err, context.DeadlineExceeded))
+	require.Less(t, int64(time.Since(start)), int64(time.Second))
+}
+
+func TestSetFormFields(t *testing.T) {
+	names := []string{"zeta", "alpha", "mike", "bravo", "yankee", "charlie"}
+
+	fields := make([]FormField, 0, len(names))
+	for _, name := range names {
+		fields = append(fields, FormField{Name: name, Value: "value of " + name})
+	}
+
+	// Start a local HTTP server
+	server := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			raw, err := ioutil.ReadAll(req.Body)
+			require.NoError(t, err)
+
+			// Parts must appear in the given order in the raw body
+			last := -1
+			for _, name := range names {
+				i := strings.Index(string(raw), fmt.Sprintf("name=%q", name))
+				require.Greater(t, i, last, name)
+				last = i
+			}
+		}),
+	)
+	defer server.Close()
+
+	r := New(context.Background(), server.URL).SetFormFields(nil, fields)
+	require.NoError(t, r.err)
+
+	_, err := r.Post()
+	require.NoError(t, err)
+}
+
+func TestSetFormFieldsReader(t *testing.T) {
+	large := strings.Repeat("large value ", 100000)
+
+	// Start a local HTTP server
+	server := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			if req.URL.Path == "/error" {
+				_, _ = ioutil.ReadAll(req.Body)
+				return
+			}
+			if req.URL.Path == "/field" {
+				require.NoError(t, req.ParseMultipartForm(1<<20))
+				require.Equal(t, "value", req.FormValue("field"))
+				return
+			}
+
+			require.NoError(t, req.ParseMultipartForm(1<<20))
+			require.Equal(t, large, req.FormValue("large"))
+			require.Equal(t, "value", req.FormValue("small"))
+
+			file, header, err := req.FormFile("file")
+			require.NoError(t, err)
+			defer file.Close()
+
+			data, err := ioutil.ReadAll(file)
+			require.NoError(t, err)
+			require.Equal(t, "report.txt", header.Filename)
+			require.Equal(t, "file content", string(data))
+		}),
+	)
+	defer server.Close()
+
+	resp, err := New(context.Background(), server.URL).SetFormFields(
+		[]FormField{{Name: "file", Value: "report.txt", Reader: strings.NewReader("file content")}},
+		[]FormField{{Name: "large", Reader: strings.NewReader(large)}, {Name: "small", Value: "value"}},
+	).Post()
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+
+	// The form is streamed once
+	r := New(context.Background(), server.URL+"/field").SetFormFields(nil, []FormField{{Name: "field", Reader: strings.NewReader("value")}})
+	require.NoError(t, r.err)
+	require.Nil(t, r.SpyBody())
+
+	_, err = r.Post()
+	require.NoError(t, err)
+	_, err = r.Post()
+	require.Error(t, err)
+
+	// Reader errors fail the request
+	_, err = New(context.Background(), server.URL+"/error").SetFormFields(nil, []FormField{{Name: "field", Reader: iotest.ErrReader(errors.New("read error"))}}).Post()
+	require.Error(t, err)
+}
+
+func TestSetMultipartBoundary(t *testing.T) {
+	const boundary = "fixed-boundary-0123456789"
+
+	// Start a local HTTP server
+	server := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			require.Equal(t, "multipart/form-data; boundary="+boundary, req.Header.Get("Content-Type"))
+
+			raw, err := ioutil.ReadAll(req.Body)
+			require.NoError(t, err)
+			require.Contains(t, string(raw), "--"+boundary+"\r\n")
+			require.Contains(t, string(raw), "--"+boundary+"--")
+		}),
+	)
+	defer server.Close()
+
+	r := New(context.Background(), server.URL).
+		SetMultipartBoundary(boundary).
+		SetFormFields(nil, []FormField{{Name: "name", Value: "value"}})
+	require.NoError(t, r.err)
+
+	_, err := r.Post()
+	require.NoError(t, err)
+
+	// Invalid boundaries
+	require.Error(t, New(context.Background(), server.URL).SetMultipartBoundary("").err)
+	require.Error(t, New(context.Background(), server.URL).SetMultipartBoundary("invalid\nboundary").err)
+	require.Error(t, New(context.Background(), server.URL).SetMultipartBoundary(strings.Repeat("x", 71)).err)
+}
+
+func TestSetBodySeeker(t *testing.T) {
+	var requests int32
+
+	// Start a local HTTP server which redirects the body to another path
+	server := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			atomic.AddInt32(&requests, 1)
+			require.Equal(t, int64(len("body")), req.ContentLength)
+
+			body, err := ioutil.ReadAll(req.Body)
+			require.NoError(t, err)
+			require.Equal(t, "body", string(body))
+
+			if req.URL.Path == "/start" {
+				http.Redirect(rw, req, "/end", http.StatusTemporaryRedirect)
+			}
+		}),
+	)
+	defer server.Close()
+
+	// Sent from the current position
+	rs := bytes.NewReader([]byte("skipped body"))
+	_, err := rs.Seek(int64(len("skipped ")), io.SeekStart)
+	require.NoError(t, err)
+
+	r := New(context.Background(), server.URL+"/start").SetBodySeeker(rs)
+	require.NoError(t, r.err)
+
+	resp, err := r.Post()
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+	require.Equal(t, int32(2), atomic.LoadInt32(&requests))
+
+	// Sent again
+	resp, err = r.Post()
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+	require.Equal(t, int32(4), atomic.LoadInt32(&requests))
+}
+
+func TestSetBodySeekerHedging(t *testing.T) {
+	content := strings.Repeat("body ", 10000)
+	var requests int32
+
+	// Start a local HTTP server which is slow for the first request only
+	server := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			body, err := ioutil.ReadAll(req.Body)
+			require.NoError(t, err)
+			require.Equal(t, content, string(body))
+
+			if atomic.AddInt32(&requests, 1) == 1 {
+				time.Sleep(200 * time.Millisecond)
+			}
+		}),
+	)
+	defer server.Close()
+
+	// Copies read their own section
+	_, err := New(context.Background(), server.URL).
+		SetHedging(50*time.Millisecond, 2).
+		SetBodySeeker(strings.NewReader(content)).
+		Put()
+	require.NoError(t, err)
+	require.Equal(t, int32(2), atomic.LoadInt32(&requests))
+
+	// Seekers without ReadAt aren't hedged
+	atomic.StoreInt32(&requests, 0)
+	_, err = New(context.Background(), server.URL).
+		SetHedging(50*time.Millisecond, 2).
+		SetBodySeeker(struct{ io.ReadSeeker }{strings.NewReader(content)}).
+		Put()
+	require.NoError(t, err)
+	require.Equal(t, int32(1), atomic.LoadInt32(&requests))
+}
+
+func TestSetBodyFromRequest(t *testing.T) {
+	content := strings.Repeat("file content ", 1000)
+
+	// Start a local HTTP server receiving the upload
+	backend := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			require.Positive(t, req.ContentLength)
+			require.True(t, strings.HasPrefix(req.Header.Get("Content-Type"), "multipart/form-data; boundary="))
+
+			file, header, err := req.FormFile("file")
+			require.NoError(t, err)
+			defer file.Close()
+
+			data, err := ioutil.ReadAll(file)
+			require.NoError(t, err)
+			require.Equal(t, "upload.txt", header.Filename)
+			require.Equal(t, content, string(data))
+			require.Equal(t, "value", req.FormValue("name"))
+		}),
+	)
+	defer backend.Close()
+
+	// Start a local HTTP server proxying the upload
+	proxy := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			resp, err := New(req.Context(), backend.URL).SetBodyFromRequest(req).Post()
+			require.NoError(t, err)
+			rw.WriteHeader(resp.StatusCode())
+		}),
+	)
+	defer proxy.Close()
+
+	path := filepath.Join(t.TempDir(), "upload.txt")
+	require.NoError(t, ioutil.WriteFile(path, []byte(content), 0600))
+
+	resp, err := New(context.Background(), proxy.URL).
+		SetFormFields([]FormField{{Name: "file", Value: path}}, []FormField{{Name: "name", Value: "value"}}).
+		Post()
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+
+	// The body can't be sent twice
+	in := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("body"))
+	r := New(context.Background(), backend.URL).SetBodyFromRequest(in)
+	_, err = r.prepare(http.MethodPost)
+	require.NoError(t, err)
+	_, err = r.prepare(http.MethodPost)
+	require.Error(t, err)
+}
+
+func TestRequest(t *testing.T) {
+	r := New(context.Background(), "http://example.com/Path?key=value").
+		SetMethod(http.MethodPost).
+		SetHeaders(map[string]string{"Test-Header": "this is a test"}).
+		SetBody([]byte(responseData))
+
+	req, err := r.Request()
+	require.NoError(t, err)
+	require.Equal(t, http.MethodPost, req.Method)
+	require.Equal(t, "http://example.com/Path?key=value", req.URL.String())
+	require.Equal(t, "this is a test", req.Header.Get("Test-Header"))
+	require.Equal(t, int64(len(responseData)), req.ContentLength)
+
+	body, err := ioutil.ReadAll(req.Body)
+	require.NoError(t, err)
+	require.Equal(t, responseData, string(body))
+
+	// Returned request is a copy
+	req.Header.Set("Test-Header", "changed")
+	require.Equal(t, "this is a test", r.request.Header.Get("Test-Header"))
+
+	// Body can be read again from a new copy
+	req, err = r.Request()
+	require.NoError(t, err)
+	body, err = ioutil.ReadAll(req.Body)
+	require.NoError(t, err)
+	require.Equal(t, responseData, string(body))
+}
+
+func TestRequestEarlyError(t *testing.T) {
+	r := &Req{err: fmt.Errorf("Test Error")}
+	req, err := r.Request()
+	require.Error(t, err)
+	require.Nil(t, req)
+}
+
+func TestEnableAutoDecompress(t *testing.T) {
+
+	// Start a local HTTP server which gzips responses when asked
+	server := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			if !strings.Contains(req.Header.Get("Accept-Encoding"), "gzip") {
+				_, err := rw.Write([]byte(responseData))
+				require.NoError(t, err)
+				return
+			}
+
+			rw.Header().Set("Content-Encoding", "gzip")
+			gw := gzip.NewWriter(rw)
+			_, err := gw.Write([]byte(responseData))
+			require.NoError(t, err)
+			require.NoError(t, gw.Close())
+		}),
+	)
+	defer server.Close()
+
+	// Unrelated headers keep transparent decompression
+	resp, err := New(context.Background(), server.URL).
+		SetHeaders(map[string]string{"Test-Header": "this is a test"}).
+		EnableAutoDecompress(true).
+		Get()
+	require.NoError(t, err)
+	body, err := resp.Body()
+	require.NoError(t, err)
+	require.Equal(t, responseData, string(body))
+
+	// User set Accept-Encoding is stripped so net/http decompresses
+	resp, err = New(context.Background(), server.URL).
+		SetHeaders(map[string]string{"Accept-Encoding": "gzip"}).
+		EnableAutoDecompress(true).
+		Get()
+	require.NoError(t, err)
+	body, err = resp.Body()
+	require.NoError(t, err)
+	require.Equal(t, responseData, string(body))
+
+	// Without it the body is returned compressed
+	resp, err = New(context.Background(), server.URL).
+		SetHeaders(map[string]string{"Accept-Encoding": "gzip"}).
+		Get()
+	require.NoError(t, err)
+	body, err = resp.Body()
+	require.NoError(t, err)
+	require.NotEqual(t, responseData, string(body))
+}
+
+func TestNewURL(t *testing.T) {
+	u := &url.URL{
+		Scheme:   "http",
+		Host:     "Example.COM",
+		Path:     "/Mixed/Case",
+		RawQuery: "Key=Value&key=value",
+	}
+
+	r := NewURL(context.Background(), u)
+
+	// Changes to the given URL don't affect the request
+	u.Path = "/changed"
+
+	req, err := r.Request()
+	require.NoError(t, err)
+	require.Equal(t, "http://Example.COM/Mixed/Case?Key=Value&key=value", req.URL.String())
+
+	r.MergeQuery(url.Values{"b": {"1"}})
+	req, err = r.Request()
+	require.NoError(t, err)
+	require.Equal(t, url.Values{"Key": {"Value"}, "key": {"value"}, "b": {"1"}}, req.URL.Query())
+	require.Equal(t, "Example.COM", req.URL.Host)
+}
+
+func TestNewURLRequest(t *testing.T) {
+
+	// Start a local HTTP server
+	server := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			require.Equal(t, "/Mixed/Case", req.URL.Path)
+			require.Equal(t, "Value", req.URL.Query().Get("Key"))
+		}),
+	)
+	defer server.Close()
+
+	u, err := url.Parse(server.URL + "/Mixed/Case?Key=Value")
+	require.NoError(t, err)
+
+	resp, err := NewURL(context.Background(), u).Get()
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+}
+
+func TestSetProxyAuth(t *testing.T) {
+	expected := "Basic " + base64.StdEncoding.EncodeToString([]byte("user:pass"))
+
+	// Start a local HTTP server acting as a proxy
+	proxy := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			if req.Header.Get("Proxy-Authorization") != expected {
+				rw.WriteHeader(http.StatusProxyAuthRequired)
+				return
+			}
+			require.Equal(t, "target.test", req.URL.Host)
+			_, err := rw.Write([]byte(responseData))
+			require.NoError(t, err)
+		}),
+	)
+	defer proxy.Close()
+
+	proxyURL, err := url.Parse(proxy.URL)
+	require.NoError(t, err)
+
+	// Credentials in the proxy URL
+	withUserinfo := *proxyURL
+	withUserinfo.User = url.UserPassword("user", "pass")
+
+	resp, err := New(context.Background(), "http://target.test/").SetProxy(withUserinfo.String()).Get()
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+
+	// Explicit credentials before and after SetProxy
+	resp, err = New(context.Background(), "http://target.test/").SetProxy(proxy.URL).SetProxyAuth("user", "pass").Get()
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+
+	resp, err = New(context.Background(), "http://target.test/").SetProxyAuth("user", "pass").SetProxy(proxy.URL).Get()
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+
+	// Wrong credentials
+	resp, err = New(context.Background(), "http://target.test/").SetProxy(proxy.URL).SetProxyAuth("user", "wrong").Get()
+	require.NoError(t, err)
+	require.Equal(t, http.StatusProxyAuthRequired, resp.StatusCode())
+}
+
+func TestSetMaxBodyReadTimeout(t *testing.T) {
+
+	// Start a local HTTP server
+	server := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			stall := req.URL.Path == "/stall"
+
+			// Send the body in chunks, gaps are longer than the timeout in total but not one by one
+			for i := 0; i < 5; i++ {
+				_, err := rw.Write([]byte("chunk"))
+				require.NoError(t, err)
+				rw.(http.Flusher).Flush()
+
+				if stall && i == 2 {
+					select {
+					case <-req.Context().Done():
+					case <-time.After(2 * time.Second):
+					}
+					return
+				}
+				time.Sleep(50 * time.Millisecond)
+			}
+		}),
+	)
+	defer server.Close()
+
+	resp, err := New(context.Background(), server.URL).SetMaxBodyReadTimeout(150 * time.Millisecond).Get()
+	require.NoError(t, err)
+
+	body, err := resp.Body()
+	require.NoError(t, err)
+	require.Equal(t, strings.Repeat("chunk", 5), string(body))
+
+	// Server stalls in the middle of the body
+	resp, err = New(context.Background(), server.URL+"/stall").SetMaxBodyReadTimeout(150 * time.Millisecond).Get()
+	require.NoError(t, err)
+
+	start := time.Now()
+	_, err = resp.Body()
+	require.Equal(t, ErrBodyReadTimeout, err)
+	require.Less(t, int64(time.Since(start)), int64(time.Second))
+}
+
+func TestSetMethodOverride(t *testing.T) {
+
+	// Start a local HTTP server
+	server := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			require.Equal(t, http.MethodPost, req.Method)
+			require.Equal(t, http.MethodPut, req.Header.Get("X-HTTP-Method-Override"))
+		}),
+	)
+	defer server.Close()
+
+	resp, err := New(context.Background(), server.URL).SetMethodOverride(http.MethodPut).Put()
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+}
+
+// roundTripperFunc is an http.RoundTripper calling the function
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestSetClient(t *testing.T) {
+
+	// Start a local HTTP server
+	server := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			require.Equal(t, "custom", req.Header.Get("Transport"))
+			_, err := rw.Write([]byte(responseData))
+			require.NoError(t, err)
+		}),
+	)
+	defer server.Close()
+
+	used := false
+	client := &http.Client{
+		Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			used = true
+			req.Header.Set("Transport", "custom")
+			return http.DefaultTransport.RoundTrip(req)
+		}),
+	}
+
+	r := New(context.Background(), server.URL).SetClient(client)
+
+	resp, err := r.Get()
+	require.NoError(t, err)
+	require.True(t, used)
+	require.Equal(t, client, r.client)
+
+	body, err := resp.Body()
+	require.NoError(t, err)
+	require.Equal(t, responseData, string(body))
+
+	// Transport setters fail instead of panicking or changing a shared transport
+	r.SetTLSConfig(&tls.Config{})
+	require.Error(t, r.err)
+
+	r = New(context.Background(), server.URL).SetClient(&http.Client{}).SetProxy("http://proxy.com:1234")
+	require.Error(t, r.err)
+
+	r = New(context.Background(), server.URL).SetClient(nil)
+	require.Error(t, r.err)
+}
+
+func TestSetBodyFunc(t *testing.T) {
+
+	// Start a local HTTP server which redirects once, the body is sent again after the redirect
+	server := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			body, err := ioutil.ReadAll(req.Body)
+			require.NoError(t, err)
+			require.Equal(t, "body", string(body))
+			require.Equal(t, int64(4), req.ContentLength)
+
+			if req.URL.Path == "/start" {
+				http.Redirect(rw, req, "/final", http.StatusTemporaryRedirect)
+			}
+		}),
+	)
+	defer server.Close()
+
+	calls := 0
+	r := New(context.Background(), server.URL+"/start").SetBodyFunc(func() (io.ReadCloser, int64, error) {
+		calls++
+		return ioutil.NopCloser(strings.NewReader("body")), 4, nil
+	})
+
+	resp, err := r.Post()
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+	require.Equal(t, 2, calls)
+
+	// Factory errors fail the request
+	_, err = New(context.Background(), server.URL).SetBodyFunc(func() (io.ReadCloser, int64, error) {
+		return nil, 0, errors.New("body error")
+	}).Post()
+	require.Error(t, err)
+}
+
+func TestSetRequestModifier(t *testing.T) {
+
+	// Start a local HTTP server
+	server := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			require.Equal(t, "modified", req.Header.Get("X-Modified"))
+			require.Equal(t, "value", req.URL.Query().Get("param"))
+
+			_, err := ioutil.ReadAll(req.Body)
+			require.NoError(t, err)
+			require.Equal(t, "checksum", req.Trailer.Get("X-Checksum"))
+		}),
+	)
+	defer server.Close()
+
+	resp, err := New(context.Background(), server.URL).
+		SetParam("param", "value").
+		SetBody([]byte("body")).
+		SetRequestModifier(func(req *http.Request) {
+			// The URL is finalized
+			require.Equal(t, "value", req.URL.Query().Get("param"))
+
+			req.Header.Set("X-Modified", "modified")
+			req.ContentLength = -1
+			req.Trailer = http.Header{"X-Checksum": []string{"checksum"}}
+		}).
+		Post()
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+}
+
+func TestSetRequestInterceptor(t *testing.T) {
+	var requests int32
+
+	// Start a local HTTP server
+	server := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			atomic.AddInt32(&requests, 1)
+			_, err := rw.Write([]byte(responseData))
+			require.NoError(t, err)
+		}),
+	)
+	defer server.Close()
+
+	interceptor := func(req *http.Request) (*Response, error) {
+		if req.URL.Path != "/cached" {
+			return nil, nil
+		}
+		return NewResponse(&http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"X-Cache": []string{"HIT"}},
+			Body:       ioutil.NopCloser(strings.NewReader("cached")),
+			Request:    req,
+		}), nil
+	}
+
+	// Synthetic response without hitting the network
+	resp, err := New(context.Background(), server.URL+"/cached").SetRequestInterceptor(interceptor).Get()
+	require.NoError(t, err)
+	require.Equal(t, "HIT", resp.Headers().Get("X-Cache"))
+
+	body, err := resp.Body()
+	require.NoError(t, err)
+	require.Equal(t, "cached", string(body))
+	require.Equal(t, int32(0), atomic.LoadInt32(&requests))
+
+	// Synthetic responses without Content-Length can be saved
+	resp, err = New(context.Background(), server.URL+"/cached").SetRequestInterceptor(interceptor).Get()
+	require.NoError(t, err)
+
+	filePath := filepath.Join(t.TempDir(), "cached")
+	require.NoError(t, resp.SaveFile(filePath))
+
+	data, err := ioutil.ReadFile(filePath)
+	require.NoError(t, err)
+	require.Equal(t, "cached", string(data))
+
+	// Requests not intercepted are sent
+	resp, err = New(context.Background(), server.URL+"/other").SetRequestInterceptor(interceptor).Get()
+	require.NoError(t, err)
+
+	body, err = resp.Body()
+	require.NoError(t, err)
+	require.Equal(t, responseData, string(body))
+	require.Equal(t, int32(1), atomic.LoadInt32(&requests))
+
+	// Interceptor errors fail the request
+	_, err = New(context.Background(), server.URL).SetRequestInterceptor(func(*http.Request) (*Response, error) {
+		return nil, errors.New("intercepted")
+	}).Get()
+	require.EqualError(t, err, "intercepted")
+	require.Equal(t, int32(1), atomic.LoadInt32(&requests))
+
+	// Bodies of intercepted requests are closed
+	var closed int32
+	_, err = New(context.Background(), server.URL+"/cached").
+		SetRequestInterceptor(interceptor).
+		SetBodyFunc(func() (io.ReadCloser, int64, error) {
+			return &closeCounter{Reader: strings.NewReader("body"), closed: &closed}, 4, nil
+		}).
+		Post()
+	require.NoError(t, err)
+	require.Equal(t, int32(1), atomic.LoadInt32(&closed))
+}
+
+func TestGetRange(t *testing.T) {
+	content := []byte("MZ\x90\x00 the rest of the file")
+
+	// Start a local HTTP server which supports ranges
+	server := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			http.ServeContent(rw, req, "file.exe", time.Time{}, bytes.NewReader(content))
+		}),
+	)
+	defer server.Close()
+
+	resp, err := New(context.Background(), server.URL).GetRange(0, 3)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusPartialContent, resp.StatusCode())
+
+	body, err := resp.Body()
+	require.NoError(t, err)
+	require.Equal(t, content[:4], body)
+
+	resp, err = New(context.Background(), server.URL).GetRange(4, 7)
+	require.NoError(t, err)
+	require.Equal(t, fmt.Sprintf("bytes 4-7/%d", len(content)), resp.Headers().Get("Content-Range"))
+
+	body, err = resp.Body()
+	require.NoError(t, err)
+	require.Equal(t, " the", string(body))
+
+	// Later requests get the whole resource
+	r := New(context.Background(), server.URL)
+	_, err = r.GetRange(0, 9)
+	require.NoError(t, err)
+
+	resp, err = r.Get()
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+
+	body, err = resp.Body()
+	require.NoError(t, err)
+	require.Equal(t, content, body)
+
+	_, err = New(context.Background(), server.URL).GetRange(5, 4)
+	require.Error(t, err)
+}
+
+func TestSpyBody(t *testing.T) {
+	data := Data{FirstName: "John", LastName: "Doe", Age: 42}
+
+	r := New(context.Background(), "http://example.com").SetBodyJSON(data)
+	require.NoError(t, r.err)
+
+	expected, err := json.Marshal(data)
+	require.NoError(t, err)
+	require.Equal(t, expected, r.SpyBody())
+	require.Equal(t, "application/json", r.request.Header.Get("Content-Type"))
+
+	// The sent body isn't changed through the copy
+	r.SpyBody()[0] = 'x'
+	req, err := r.Request()
+	require.NoError(t, err)
+	body, err := ioutil.ReadAll(req.Body)
+	require.NoError(t, err)
+	require.Equal(t, expected, body)
+
+	require.Equal(t, []byte("raw"), r.SetBody([]byte("raw")).SpyBody())
+	require.Nil(t, r.SetBodyFunc(func() (io.ReadCloser, int64, error) { return nil, 0, nil }).SpyBody())
+
+	// Unsupported values fail the chain
+	require.Error(t, New(context.Background(), "http://example.com").SetBodyJSON(make(chan int)).err)
+}
+
+func TestSetBodyJSONStream(t *testing.T) {
+	items := make([]Data, 10000)
+	for i := range items {
+		items[i] = Data{FirstName: "John", LastName: "Doe", Age: i}
+	}
+
+	// Start a local HTTP server which redirects once so the body is sent again
+	server := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			require.Equal(t, "application/json", req.Header.Get("Content-Type"))
+			require.Equal(t, int64(-1), req.ContentLength)
+
+			var decoded []Data
+			require.NoError(t, json.NewDecoder(req.Body).Decode(&decoded))
+			require.Equal(t, items, decoded)
+
+			if req.URL.Path == "/start" {
+				http.Redirect(rw, req, "/final", http.StatusTemporaryRedirect)
+			}
+		}),
+	)
+	defer server.Close()
+
+	resp, err := New(context.Background(), server.URL+"/start").SetBodyJSONStream(items).Post()
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+	require.Equal(t, "/final", resp.Response().Request.URL.Path)
+
+	// Encoding errors fail the request
+	errServer := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {}))
+	defer errServer.Close()
+
+	_, err = New(context.Background(), errServer.URL).SetBodyJSONStream(make(chan int)).Post()
+	require.Error(t, err)
+}
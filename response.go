@@ -2,20 +2,88 @@ package httpreq
 
 import (
 	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
 	"errors"
 	"fmt"
+	"hash"
 	"io"
 	"io/ioutil"
 	"mime"
 	"net/http"
 	"os"
 	"path"
+	"path/filepath"
+	"strings"
 )
 
 // Response is the main struct which holds the http.Response and data.
 type Response struct {
-	resp *http.Response
-	data []byte
+	resp   *http.Response
+	data   []byte
+	logger Logger
+
+	// bodyRead is true once the body is read into data, even if it's empty
+	bodyRead bool
+
+	// noBuffer forbids reading the whole body into memory, see Req.SetDoNotBuffer
+	noBuffer bool
+
+	// maxPartSize limits the parts read by MultipartParts, see Req.SetMaxPartSize
+	maxPartSize int64
+
+	// jsonDisallowUnknown and jsonUseNumber configure DecodeJSON, see Req.SetJSONDecoderOptions
+	jsonDisallowUnknown bool
+	jsonUseNumber       bool
+
+	// decodeContent decodes the body according to Content-Encoding when it's read, see Req.SetAcceptEncoding
+	decodeContent bool
+
+	// manualClose leaves closing the body to the caller, see Req.SetManualClose
+	manualClose bool
+
+	// maxResponseSize limits the size of the body, see Req.SetMaxResponseSize
+	maxResponseSize int64
+
+	// gunzipDownloads saves gzip encoded downloads decompressed, see Req.GunzipDownloads
+	gunzipDownloads bool
+
+	// maxExtractSize limits the size of the files extracted by ExtractArchive, see Req.SetMaxExtractSize
+	maxExtractSize int64
+
+	// err is the error the request returned with the response
+	err error
+
+	// counter and the counts before the request for BytesRead and BytesWritten
+	counter      *byteCounter
+	readStart    int64
+	writtenStart int64
+}
+
+// ErrContentLengthMismatch is returned when the body is shorter or longer than its Content-Length header,
+// i.e. a truncated download
+var ErrContentLengthMismatch = errors.New("response body length doesn't match Content-Length")
+
+// ErrResponseTooLarge is returned when the response body exceeds the allowed size
+var ErrResponseTooLarge = errors.New("response body is too large")
+
+// ErrBufferingDisabled is returned when the body is read into memory after Req.SetDoNotBuffer
+var ErrBufferingDisabled = errors.New("response body buffering is disabled, use Reader() to stream it")
+
+// NewResponse creates a Response from an http.Response, e.g. a cached or synthetic one
+// returned by a request interceptor. A zero ContentLength without a Content-Length header is
+// taken as unknown (-1), so VerifyContentLength and SaveFile accept bodies of hand built responses.
+func NewResponse(resp *http.Response) *Response {
+	if resp != nil && resp.ContentLength == 0 && resp.Header.Get("Content-Length") == "" {
+		resp.ContentLength = -1
+	}
+	return &Response{resp: resp}
 }
 
 // Response returns the original http.Response
@@ -39,11 +107,349 @@ func (r *Response) Headers() http.Header {
 	return r.resp.Header
 }
 
+// Err returns the error the request returned with this response, i.e. an *HTTPError after
+// Req.FailOnError, so a Response passed on alone still tells whether it failed
+func (r *Response) Err() error {
+	if r == nil {
+		return nil
+	}
+	return r.err
+}
+
+// Cookies returns the cookies set by the response, parsing every Set-Cookie header.
+// Cookies set in trailers of chunked responses are included once the body is read.
+func (r *Response) Cookies() []*http.Cookie {
+	if r == nil || r.resp == nil {
+		return nil
+	}
+
+	cookies := r.resp.Cookies()
+	if values := r.resp.Trailer.Values("Set-Cookie"); len(values) > 0 {
+		trailer := &http.Response{Header: http.Header{"Set-Cookie": values}}
+		cookies = append(cookies, trailer.Cookies()...)
+	}
+	return cookies
+}
+
+// Reader returns the response body to stream it, the caller must close it.
+// If the body has already been read, the cached body is returned.
+func (r *Response) Reader() (io.ReadCloser, error) {
+	if r == nil || r.resp == nil || r.resp.Body == nil {
+		err := errors.New("http.Response or its Body is nil")
+		r.log().Errorf("%v", err)
+		return nil, err
+	}
+
+	if r.bodyRead {
+		return ioutil.NopCloser(bytes.NewReader(r.data)), nil
+	}
+
+	return r.resp.Body, nil
+}
+
+// WriteTo streams the response body to w and closes it, i.e. to proxy a response downstream
+// to an http.ResponseWriter. It implements io.WriterTo. The body isn't buffered unless the
+// encodings requested by Req.SetAcceptEncoding must be decoded.
+func (r *Response) WriteTo(w io.Writer) (int64, error) {
+	if r != nil && r.decodeContent && !r.bodyRead {
+		data, err := r.readBody()
+		if err != nil {
+			return 0, err
+		}
+		n, err := w.Write(data)
+		return int64(n), err
+	}
+
+	body, err := r.Reader()
+	if err != nil {
+		return 0, err
+	}
+	defer r.closeBody(body)
+
+	return io.Copy(w, limitBody(body, r.maxResponseSize))
+}
+
+// BytesRead returns the number of bytes received on the connection for this request so far,
+// including headers. It requires Req.CountBytes, otherwise it's 0.
+func (r *Response) BytesRead() int64 {
+	if r == nil || r.counter == nil {
+		return 0
+	}
+	return r.counter.Read() - r.readStart
+}
+
+// BytesWritten returns the number of bytes sent on the connection for this request,
+// including headers. It requires Req.CountBytes, otherwise it's 0.
+func (r *Response) BytesWritten() int64 {
+	if r == nil || r.counter == nil {
+		return 0
+	}
+	return r.counter.Written() - r.writtenStart
+}
+
 // Body returns the response body
 func (r *Response) Body() ([]byte, error) {
 	body, err := r.readBody()
 	if err != nil {
-		logger.Errorf("Can not read http.Response body Error: %v", err)
+		r.log().Errorf("Can not read http.Response body Error: %v", err)
+		return nil, err
+	}
+	return body, nil
+}
+
+// VerifyContentLength reads the body and returns ErrContentLengthMismatch if its length differs
+// from the Content-Length header, which catches truncated downloads. Bodies without a declared
+// length (chunked or decompressed transparently) aren't checked.
+func (r *Response) VerifyContentLength() error {
+	data, err := r.readBody()
+	return r.verifyLength(int64(len(data)), err)
+}
+
+// verifyLength checks the n bytes read from the body, until the read error err, against the Content-Length
+func (r *Response) verifyLength(n int64, err error) error {
+	if err != nil {
+
+		// net/http fails reading a body shorter than its Content-Length
+		if errors.Is(err, io.ErrUnexpectedEOF) && r.resp.ContentLength > 0 {
+			err = fmt.Errorf("%w: %v", ErrContentLengthMismatch, err)
+		}
+		return err
+	}
+
+	// Responses to HEAD requests declare the length of the body they don't have
+	if r.resp.Request != nil && r.resp.Request.Method == http.MethodHead {
+		return nil
+	}
+
+	if r.resp.ContentLength >= 0 && n != r.resp.ContentLength {
+		err = fmt.Errorf("%w: read %d bytes, Content-Length is %d", ErrContentLengthMismatch, n, r.resp.ContentLength)
+		r.log().Errorf("%v", err)
+		return err
+	}
+	return nil
+}
+
+// BodyContext returns the response body like Body, but the read is interrupted by closing the body
+// when ctx is done, so a stalled connection doesn't block the caller
+func (r *Response) BodyContext(ctx context.Context) ([]byte, error) {
+	body, err := r.readBodyTee(ctx, nil)
+	if err != nil {
+		r.log().Errorf("Can not read http.Response body Error: %v", err)
+		return nil, err
+	}
+	return body, nil
+}
+
+// DecompressedBody returns the body decoded according to the Content-Encoding header (gzip or deflate).
+// It's needed when transparent decompression is disabled or the server compressed the body without being asked.
+func (r *Response) DecompressedBody() ([]byte, error) {
+	body, err := r.Body()
+	if err != nil {
+		return nil, err
+	}
+
+	body, err = decodeContent(body, r.Headers().Get("Content-Encoding"))
+	if err != nil {
+		r.log().Errorf("Can't decompress http.Response body Error: %v", err)
+		return nil, err
+	}
+
+	return body, nil
+}
+
+// BodySeeker returns the body as an io.ReadSeeker to seek and re-read it, i.e. to try multiple parsers.
+// The whole body is read into memory first.
+func (r *Response) BodySeeker() (io.ReadSeeker, error) {
+	body, err := r.Body()
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(body), nil
+}
+
+// BodyEquals reports whether the response body is equal to expected
+func (r *Response) BodyEquals(expected []byte) (bool, error) {
+	body, err := r.Body()
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(body, expected), nil
+}
+
+// BodyContains reports whether the response body contains substr
+func (r *Response) BodyContains(substr string) (bool, error) {
+	body, err := r.Body()
+	if err != nil {
+		return false, err
+	}
+	return bytes.Contains(body, []byte(substr)), nil
+}
+
+// String returns the response body as a UTF-8 string, transcoding it
+// according to the charset declared in the Content-Type header
+func (r *Response) String() (string, error) {
+	body, err := r.utf8Body()
+	if err != nil {
+		return "", err
+	}
+	return string(body), nil
+}
+
+// BodyJSONPretty returns the JSON response body indented for readable logging,
+// keeping the order of keys and the exact numbers of the body
+func (r *Response) BodyJSONPretty() (string, error) {
+	body, err := r.utf8Body()
+	if err != nil {
+		return "", err
+	}
+
+	var b bytes.Buffer
+	if err = json.Indent(&b, body, "", "  "); err != nil {
+		err = fmt.Errorf("response body is not valid JSON: %w", err)
+		r.log().Errorf("%v", err)
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// DecodeJSON unmarshals the JSON response body into v.
+// Bodies declared with a non UTF-8 charset are transcoded to UTF-8 first.
+// The body is read from the network once and cached, but every call unmarshals it again,
+// so decode once and reuse the result when possible.
+func (r *Response) DecodeJSON(v interface{}) error {
+	body, err := r.utf8Body()
+	if err != nil {
+		return err
+	}
+
+	if err = r.unmarshalJSON(body, v); err != nil {
+		r.log().Errorf("Can't decode JSON response Error: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+// StreamJSONArray decodes a top-level JSON array body element by element, sending each to out
+// without reading the whole array into memory. out is closed when it returns, decode errors
+// are returned after the elements before them are sent.
+func (r *Response) StreamJSONArray(out chan<- json.RawMessage) error {
+	defer close(out)
+
+	body, err := r.Reader()
+	if err != nil {
+		return err
+	}
+	defer r.closeBody(body)
+
+	dec := json.NewDecoder(body)
+
+	if t, err := dec.Token(); err != nil || t != json.Delim('[') {
+		if err == nil {
+			err = fmt.Errorf("response body is not a JSON array, it starts with %v", t)
+		}
+		r.log().Errorf("Can't decode JSON array response Error: %v", err)
+		return err
+	}
+
+	for dec.More() {
+		var element json.RawMessage
+		if err := dec.Decode(&element); err != nil {
+			r.log().Errorf("Can't decode JSON array response Error: %v", err)
+			return err
+		}
+		out <- element
+	}
+
+	// Closing bracket
+	if _, err := dec.Token(); err != nil {
+		r.log().Errorf("Can't decode JSON array response Error: %v", err)
+		return err
+	}
+	return nil
+}
+
+// unmarshalJSON unmarshals data into v with the options set by Req.SetJSONDecoderOptions
+func (r *Response) unmarshalJSON(data []byte, v interface{}) error {
+	if !r.jsonDisallowUnknown && !r.jsonUseNumber {
+		return json.Unmarshal(data, v)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if r.jsonDisallowUnknown {
+		dec.DisallowUnknownFields()
+	}
+	if r.jsonUseNumber {
+		dec.UseNumber()
+	}
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+
+	// Like json.Unmarshal, reject data after the value
+	if _, err := dec.Token(); err != io.EOF {
+		return errors.New("invalid data after top-level JSON value")
+	}
+	return nil
+}
+
+// DecodeXML unmarshals the XML response body into v.
+// The charset in the Content-Type header takes precedence over the XML declaration.
+func (r *Response) DecodeXML(v interface{}) error {
+	body, err := r.readBody()
+	if err != nil {
+		r.log().Errorf("Can not read http.Response body Error: %v", err)
+		return err
+	}
+
+	d := xml.NewDecoder(bytes.NewReader(body))
+	d.CharsetReader = charsetReader
+
+	if charset := contentTypeCharset(r.Headers().Get("Content-Type")); charset != "" && !isUTF8Charset(charset) {
+		body, err = toUTF8(body, charset)
+		if err != nil {
+			r.log().Errorf("Can't transcode response body Error: %v", err)
+			return err
+		}
+
+		// Body is UTF-8 now, so ignore the encoding in the XML declaration
+		d = xml.NewDecoder(bytes.NewReader(body))
+		d.CharsetReader = utf8Reader
+	}
+
+	if err = d.Decode(v); err != nil {
+		r.log().Errorf("Can't decode XML response Error: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+// utf8Body reads the body and transcodes it to UTF-8 using the Content-Type charset
+func (r *Response) utf8Body() ([]byte, error) {
+	body, err := r.readBody()
+	if err != nil {
+		r.log().Errorf("Can not read http.Response body Error: %v", err)
+		return nil, err
+	}
+
+	charset := contentTypeCharset(r.Headers().Get("Content-Type"))
+
+	body, err = toUTF8(body, charset)
+	if err != nil {
+		r.log().Errorf("Can't transcode response body Error: %v", err)
+		return nil, err
+	}
+
+	return body, nil
+}
+
+// TeeBody returns the response body while writing it to w as it's read.
+// If the body has already been read, the cached body is written to w.
+func (r *Response) TeeBody(w io.Writer) ([]byte, error) {
+	body, err := r.readBodyTee(context.Background(), w)
+	if err != nil {
+		r.log().Errorf("Can not read http.Response body Error: %v", err)
 		return nil, err
 	}
 	return body, nil
@@ -52,51 +458,117 @@ func (r *Response) Body() ([]byte, error) {
 // DownloadFile looks for Content-Disposition header to find the filename attribute and returns the content-type
 // header with saved file path that is saved under given downloadDir.
 func (r *Response) DownloadFile(downloadDir string) (contentType string, filePath string, err error) {
+	return r.DownloadFileLimit(downloadDir, 0)
+}
+
+// DownloadFileLimit is DownloadFile failing with ErrResponseTooLarge when the body exceeds
+// maxBytes, the partial file is removed. 0 means no limit.
+func (r *Response) DownloadFileLimit(downloadDir string, maxBytes int64) (contentType string, filePath string, err error) {
 	headers := r.Headers()
 	if headers == nil {
 		err = errors.New("http response headers missing")
-		logger.Errorf("%v", err)
+		r.log().Errorf("%v", err)
 		return "", "", err
 	}
 
 	contentType = headers.Get("Content-Type")
 
-	disposition := headers.Get("Content-Disposition")
-	if disposition == "" {
-		err = errors.New("content-disposition header missing")
-		logger.Errorf("%v", err)
+	fileName, err := dispositionFilename(headers.Get("Content-Disposition"))
+	if err != nil {
+		r.log().Errorf("%v", err)
 		return contentType, "", err
 	}
 
-	_, params, err := mime.ParseMediaType(disposition)
-	if err != nil {
-		logger.Errorf("mime.ParseMediaType error: %v", err)
+	// The server controls the filename, it's reduced to its base name as in Save
+	// so it can't point outside of downloadDir
+	if fileName = baseName(fileName); fileName == "" {
+		err = errors.New("invalid filename in content-disposition")
+		r.log().Errorf("%v", err)
 		return contentType, "", err
 	}
 
-	fileName := params["filename"]
-	if fileName == "" {
-		err = errors.New("filename missing in content-disposition")
-		logger.Errorf("%v", err)
-		return contentType, "", err
+	// The file is saved decompressed, i.e. archive.tar.gz as archive.tar
+	if r.gunzipDownloads {
+		gunzipped, err := r.gunzipBody()
+		if err != nil {
+			r.log().Errorf("Can't decompress http.Response body Error: %v", err)
+			return contentType, "", err
+		}
+		if n := len(fileName) - len(".gz"); (gunzipped || r.resp.Uncompressed) && n > 0 && strings.EqualFold(fileName[n:], ".gz") {
+			fileName = fileName[:n]
+		}
 	}
 
 	filePath = path.Join(downloadDir, fileName)
 
-	err = r.SaveFile(filePath)
+	if maxBytes > 0 {
+		err = r.SaveFileLimit(filePath, maxBytes)
+	} else {
+		err = r.SaveFile(filePath)
+	}
 	if err != nil {
-		logger.Errorf("cannot save file error: %v", err)
+		r.log().Errorf("cannot save file error: %v", err)
 		return contentType, "", err
 	}
 
 	return contentType, filePath, nil
 }
 
-// SaveFile reads body and then saves the file defined in body
+// Save saves the body under dir and returns the saved file path. The filename is taken from the
+// Content-Disposition header, then the last element of the URL path and finally it's "download"
+// with an extension by the Content-Type.
+func (r *Response) Save(dir string) (string, error) {
+	filePath := filepath.Join(dir, r.filename())
+
+	if err := r.SaveFile(filePath); err != nil {
+		r.log().Errorf("cannot save file error: %v", err)
+		return "", err
+	}
+
+	return filePath, nil
+}
+
+// filename derives a filename for saving the body, see Save
+func (r *Response) filename() string {
+	headers := r.Headers()
+
+	if name, err := dispositionFilename(headers.Get("Content-Disposition")); err == nil {
+		if name = baseName(name); name != "" {
+			return name
+		}
+	}
+
+	if r.resp != nil && r.resp.Request != nil && r.resp.Request.URL != nil {
+		if name := baseName(r.resp.Request.URL.Path); name != "" {
+			return name
+		}
+	}
+
+	name := "download"
+	if mediaType, _, err := mime.ParseMediaType(headers.Get("Content-Type")); err == nil {
+		if extensions, err := mime.ExtensionsByType(mediaType); err == nil && len(extensions) > 0 {
+			name += extensions[0]
+		}
+	}
+
+	return name
+}
+
+// SaveFile reads body and then saves the file defined in body.
+// With Req.SetMaxResponseSize the body is streamed to the file like SaveFileLimit.
 func (r *Response) SaveFile(filePath string) error {
+	if r.maxResponseSize > 0 && !r.bodyRead {
+		return r.SaveFileLimit(filePath, r.maxResponseSize)
+	}
+
+	if err := r.VerifyContentLength(); err != nil {
+		r.log().Errorf("Can not save response to file %s Error: %v", filePath, err)
+		return err
+	}
+
 	data, err := r.readBody()
 	if err != nil {
-		logger.Errorf("Can not save response to file %s Error: %v", filePath, err)
+		r.log().Errorf("Can not save response to file %s Error: %v", filePath, err)
 		return err
 	}
 
@@ -107,26 +579,134 @@ func (r *Response) SaveFile(filePath string) error {
 
 	f, err := os.Create(filePath)
 	if err != nil {
-		logger.Errorf("Can not create file %s Error: %v", filePath, err)
+		r.log().Errorf("Can not create file %s Error: %v", filePath, err)
 		return err
 	}
 	defer f.Close()
 
 	_, err = io.Copy(f, bytes.NewReader(data))
 	if err != nil {
-		logger.Errorf("Can write to file %s Error: %v", filePath, err)
+		r.log().Errorf("Can write to file %s Error: %v", filePath, err)
 		return err
 	}
 
 	err = f.Sync()
 	if err != nil {
-		logger.Errorf("Can't sync file %s Error: %v", filePath, err)
+		r.log().Errorf("Can't sync file %s Error: %v", filePath, err)
 		return err
 	}
 
 	return err
 }
 
+// SaveFileLimit streams the body to filePath and fails with ErrResponseTooLarge once it exceeds
+// maxBytes, removing the partial file, so huge or hostile resources can't fill the disk. 0 means
+// no limit other than Req.SetMaxResponseSize. Unlike SaveFile, the body is not read into memory
+// unless the encodings requested by Req.SetAcceptEncoding must be decoded.
+func (r *Response) SaveFileLimit(filePath string, maxBytes int64) error {
+	if err := r.saveBody(filePath, maxBytes, nil); err != nil {
+		r.log().Errorf("Can not save response to file %s Error: %v", filePath, err)
+		return err
+	}
+	return nil
+}
+
+// SaveFileAndHash streams the body to filePath while hashing it and returns the hex digest.
+// Supported algorithms are md5, sha1, sha256 and sha512. Like SaveFileLimit, the body is limited
+// by Req.SetMaxResponseSize and not read into memory, so it can't be read again unless it was already read.
+func (r *Response) SaveFileAndHash(filePath, algo string) (hexDigest string, err error) {
+	h, err := newHash(algo)
+	if err != nil {
+		r.log().Errorf("%v", err)
+		return "", err
+	}
+
+	if err = r.saveBody(filePath, 0, h); err != nil {
+		r.log().Errorf("Can not save response to file %s Error: %v", filePath, err)
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// saveBody streams the body to filePath, and to w if it's not nil, failing with ErrResponseTooLarge
+// once it exceeds maxBytes or Req.SetMaxResponseSize. Truncated and empty bodies fail like in SaveFile.
+// The file is removed on failure.
+func (r *Response) saveBody(filePath string, maxBytes int64, w io.Writer) error {
+	if r.maxResponseSize > 0 && (maxBytes <= 0 || r.maxResponseSize < maxBytes) {
+		maxBytes = r.maxResponseSize
+	}
+
+	if maxBytes > 0 && r.resp != nil && r.resp.ContentLength > maxBytes {
+		return ErrResponseTooLarge
+	}
+
+	// Encoded bodies are decoded in memory by readBody
+	var body io.Reader
+	if r.decodeContent && !r.bodyRead {
+		data, err := r.readBody()
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	} else {
+		rc, err := r.Reader()
+		if err != nil {
+			return err
+		}
+		defer r.closeBody(rc)
+		body = rc
+	}
+
+	f, err := os.Create(filePath)
+	if err != nil {
+		return err
+	}
+
+	var dst io.Writer = f
+	if w != nil {
+		dst = io.MultiWriter(f, w)
+	}
+
+	n, err := io.Copy(dst, limitBody(body, maxBytes))
+	if !errors.Is(err, ErrResponseTooLarge) {
+		err = r.verifyLength(n, err)
+	}
+	if err == nil && n == 0 {
+		err = errors.New("Downloaded file is empty. Can not save empty response to file " + filePath)
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		_ = os.Remove(filePath)
+		return err
+	}
+	return nil
+}
+
+// Clone reads the body if it isn't read yet and returns a copy of the response sharing the body
+// bytes and headers, so the same response can be handed to multiple consumers
+func (r *Response) Clone() (*Response, error) {
+	data, err := r.readBody()
+	if err != nil {
+		r.log().Errorf("Can not clone http.Response Error: %v", err)
+		return nil, err
+	}
+
+	resp := *r.resp
+	resp.Body = ioutil.NopCloser(bytes.NewReader(data))
+
+	clone := *r
+	clone.resp = &resp
+
+	return &clone, nil
+}
+
 // Close closes the http.Response
 func (r *Response) Close() error {
 	if r == nil || r.resp == nil || r.resp.Body == nil {
@@ -135,49 +715,163 @@ func (r *Response) Close() error {
 
 	err := r.resp.Body.Close()
 	if err != nil {
-		logger.Errorf("Can't close http response body Error: %v", err)
+		r.log().Errorf("Can't close http response body Error: %v", err)
 		return err
 	}
 
 	return nil
 }
 
+// closeBody closes a body after it's read, unless Req.SetManualClose leaves closing to the caller
+func (r *Response) closeBody(body io.Closer) error {
+	if r.manualClose {
+		return nil
+	}
+	return body.Close()
+}
+
+// newHash returns a hash.Hash for the algorithm name
+func newHash(algo string) (hash.Hash, error) {
+	switch strings.ToLower(algo) {
+	case "md5":
+		return md5.New(), nil
+	case "sha1":
+		return sha1.New(), nil
+	case "sha256":
+		return sha256.New(), nil
+	case "sha512":
+		return sha512.New(), nil
+	}
+	return nil, fmt.Errorf("unsupported hash algorithm: %s", algo)
+}
+
+// dispositionFilename returns the filename attribute of a Content-Disposition header
+func dispositionFilename(disposition string) (string, error) {
+	if disposition == "" {
+		return "", errors.New("content-disposition header missing")
+	}
+
+	_, params, err := mime.ParseMediaType(disposition)
+	if err != nil {
+		return "", fmt.Errorf("mime.ParseMediaType error: %w", err)
+	}
+
+	fileName := params["filename"]
+	if fileName == "" {
+		return "", errors.New("filename missing in content-disposition")
+	}
+
+	return fileName, nil
+}
+
+// isJSONContentType reports whether contentType is application/json or a JSON based type like application/problem+json
+func isJSONContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// baseName returns the last element of a slash separated name, or empty string if there is none
+func baseName(name string) string {
+	name = path.Base(strings.ReplaceAll(name, "\\", "/"))
+	switch name {
+	case ".", "..", "/":
+		return ""
+	}
+	return name
+}
+
+// log returns the logger of the request which created this response
+func (r *Response) log() Logger {
+	if r == nil || r.logger == nil {
+		return logger
+	}
+	return r.logger
+}
+
 // readBody reads the http.Response body and assigns it to the r.Body
 func (r *Response) readBody() ([]byte, error) {
+	return r.readBodyTee(context.Background(), nil)
+}
+
+// readBodyTee reads the http.Response body like readBody, copying it to w if w is not nil
+func (r *Response) readBodyTee(ctx context.Context, w io.Writer) ([]byte, error) {
 
 	// If r.data already set then return r.data
-	if len(r.data) != 0 {
+	if len(r.data) != 0 || r.bodyRead {
+		if w != nil {
+			if _, err := w.Write(r.data); err != nil {
+				r.log().Errorf("Can't write http.Response body Error: %v", err)
+				return nil, err
+			}
+		}
 		return r.data, nil
 	}
 
+	if r.noBuffer {
+		r.log().Errorf("%v", ErrBufferingDisabled)
+		return nil, ErrBufferingDisabled
+	}
+
 	// Check if Response.resp (*http.Response) is nil
 	if r.resp == nil {
 		err := fmt.Errorf("http.Response is nil")
-		logger.Errorf("%v", err)
+		r.log().Errorf("%v", err)
 		return nil, err
 	}
 
 	// Check if Response.resp.Body (*http.Response.Body) is nil
 	if r.resp.Body == nil {
 		err := fmt.Errorf("http.Response's Body is nil")
-		logger.Errorf("%v", err)
+		r.log().Errorf("%v", err)
 		return nil, err
 	}
 
+	var body io.Reader = r.resp.Body
+	if r.maxResponseSize > 0 {
+		if r.resp.ContentLength > r.maxResponseSize {
+			r.log().Errorf("Can't read http.Response body Error: %v", ErrResponseTooLarge)
+			return nil, ErrResponseTooLarge
+		}
+
+		body = limitBody(body, r.maxResponseSize)
+	}
+	if w != nil {
+		body = io.TeeReader(body, w)
+	}
+
 	// Read response body
-	b, err := ioutil.ReadAll(r.resp.Body)
+	b, err := readAllContext(ctx, body, r.resp.Body)
+	if errors.Is(err, ErrResponseTooLarge) {
+		_ = r.closeBody(r.resp.Body)
+	}
 	if err != nil {
-		logger.Errorf("Can't read http.Response body Error: %v", err)
+		r.log().Errorf("Can't read http.Response body Error: %v", err)
 		return nil, err
 	}
 
+	// Decode the encodings requested by Req.SetAcceptEncoding like net/http does for gzip
+	if r.decodeContent {
+		if b, err = decodeContent(b, r.resp.Header.Get("Content-Encoding")); err != nil {
+			r.log().Errorf("Can't decompress http.Response body Error: %v", err)
+			return nil, err
+		}
+		r.resp.Header.Del("Content-Encoding")
+		r.resp.Header.Del("Content-Length")
+		r.resp.ContentLength = -1
+		r.resp.Uncompressed = true
+	}
+
 	// Set response readBody
 	r.data = b
+	r.bodyRead = true
 
 	// Close response body
-	err = r.resp.Body.Close()
+	err = r.closeBody(r.resp.Body)
 	if err != nil {
-		logger.Errorf("Can't close http.Response body Error: %v", err)
+		r.log().Errorf("Can't close http.Response body Error: %v", err)
 		return nil, err
 	}
 
@@ -3,14 +3,22 @@ package httpreq
 import (
 	"bytes"
 	"context"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"math/rand"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -195,6 +203,167 @@ func TestDownloadFile(t *testing.T) {
 	require.NoError(t, resp.Close())
 }
 
+func TestDownloadFileLimit(t *testing.T) {
+	url, content, downloadDir := testSetupDownloadFile(t, "binary/octet-stream",
+		func(f string) string { return fmt.Sprintf("attachment;filename=%q", f) })
+
+	resp, err := New(context.Background(), url).Get()
+	require.NoError(t, err)
+
+	_, filePath, err := resp.DownloadFileLimit(downloadDir, int64(len(content)))
+	require.NoError(t, err)
+	data, err := ioutil.ReadFile(filePath)
+	require.NoError(t, err)
+	require.Equal(t, content, string(data))
+	require.NoError(t, os.Remove(filePath))
+
+	resp, err = New(context.Background(), url).Get()
+	require.NoError(t, err)
+
+	_, _, err = resp.DownloadFileLimit(downloadDir, 10)
+	require.ErrorIs(t, err, ErrResponseTooLarge)
+
+	entries, err := ioutil.ReadDir(downloadDir)
+	require.NoError(t, err)
+	require.Empty(t, entries)
+}
+
+func TestSaveFileLimit(t *testing.T) {
+
+	// Start a local HTTP server streaming a body of unknown length
+	server := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			for i := 0; i < 10; i++ {
+				_, _ = rw.Write([]byte(responseData))
+				rw.(http.Flusher).Flush()
+			}
+		}),
+	)
+	defer server.Close()
+
+	filePath := filepath.Join(t.TempDir(), "file")
+
+	resp, err := New(context.Background(), server.URL).Get()
+	require.NoError(t, err)
+	require.Equal(t, int64(-1), resp.Response().ContentLength)
+
+	err = resp.SaveFileLimit(filePath, int64(len(responseData)*5))
+	require.ErrorIs(t, err, ErrResponseTooLarge)
+	_, err = os.Stat(filePath)
+	require.True(t, os.IsNotExist(err))
+
+	resp, err = New(context.Background(), server.URL).Get()
+	require.NoError(t, err)
+	require.NoError(t, resp.SaveFileLimit(filePath, int64(len(responseData)*10)))
+
+	data, err := ioutil.ReadFile(filePath)
+	require.NoError(t, err)
+	require.Len(t, data, len(responseData)*10)
+}
+
+func TestWriteTo(t *testing.T) {
+
+	// Start a local HTTP server
+	server := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			_, _ = rw.Write([]byte(responseData))
+		}),
+	)
+	defer server.Close()
+
+	resp, err := New(context.Background(), server.URL).Get()
+	require.NoError(t, err)
+
+	var w io.WriterTo = resp
+	var buf bytes.Buffer
+	n, err := w.WriteTo(&buf)
+	require.NoError(t, err)
+	require.Equal(t, int64(len(responseData)), n)
+	require.Equal(t, responseData, buf.String())
+
+	// Proxying downstream after the body is read
+	resp, err = New(context.Background(), server.URL).Get()
+	require.NoError(t, err)
+	_, err = resp.Body()
+	require.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	_, err = resp.WriteTo(rec)
+	require.NoError(t, err)
+	require.Equal(t, responseData, rec.Body.String())
+
+	resp, err = New(context.Background(), server.URL).SetMaxResponseSize(5).Get()
+	require.NoError(t, err)
+	_, err = resp.WriteTo(&buf)
+	require.ErrorIs(t, err, ErrResponseTooLarge)
+
+	_, err = NewResponse(nil).WriteTo(&buf)
+	require.Error(t, err)
+}
+
+func TestSetMaxResponseSize(t *testing.T) {
+
+	// Start a local HTTP server streaming a body of unknown length or with Content-Length
+	server := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			if req.URL.Path == "/length" {
+				rw.Header().Set("Content-Length", strconv.Itoa(len(responseData)*10))
+			}
+			for i := 0; i < 10; i++ {
+				_, _ = rw.Write([]byte(responseData))
+				rw.(http.Flusher).Flush()
+			}
+		}),
+	)
+	defer server.Close()
+
+	filePath := filepath.Join(t.TempDir(), "file")
+	limit := int64(len(responseData) * 5)
+
+	// SaveFile aborts the copy and removes the partial file
+	for _, path := range []string{"/", "/length"} {
+		resp, err := New(context.Background(), server.URL+path).SetMaxResponseSize(limit).Get()
+		require.NoError(t, err)
+
+		err = resp.SaveFile(filePath)
+		require.ErrorIs(t, err, ErrResponseTooLarge, path)
+		_, err = os.Stat(filePath)
+		require.True(t, os.IsNotExist(err))
+
+		resp, err = New(context.Background(), server.URL+path).SetMaxResponseSize(limit).Get()
+		require.NoError(t, err)
+		_, err = resp.Body()
+		require.ErrorIs(t, err, ErrResponseTooLarge, path)
+	}
+
+	// The smaller limit applies
+	resp, err := New(context.Background(), server.URL).SetMaxResponseSize(limit).Get()
+	require.NoError(t, err)
+	require.ErrorIs(t, resp.SaveFileLimit(filePath, limit*4), ErrResponseTooLarge)
+
+	resp, err = New(context.Background(), server.URL).SetMaxResponseSize(limit).Get()
+	require.NoError(t, err)
+	_, err = resp.SaveFileAndHash(filePath, "sha256")
+	require.ErrorIs(t, err, ErrResponseTooLarge)
+	_, err = os.Stat(filePath)
+	require.True(t, os.IsNotExist(err))
+
+	// Bodies within the limit
+	resp, err = New(context.Background(), server.URL).SetMaxResponseSize(limit * 2).Get()
+	require.NoError(t, err)
+	require.NoError(t, resp.SaveFile(filePath))
+
+	data, err := ioutil.ReadFile(filePath)
+	require.NoError(t, err)
+	require.Len(t, data, len(responseData)*10)
+
+	resp, err = New(context.Background(), server.URL).SetMaxResponseSize(limit * 2).Get()
+	require.NoError(t, err)
+	body, err := resp.Body()
+	require.NoError(t, err)
+	require.Len(t, body, len(responseData)*10)
+}
+
 func TestDownloadFile_MissingHeader(t *testing.T) {
 
 	url, _, downloadDir := testSetupDownloadFile(t, "", nil)
@@ -230,6 +399,84 @@ func TestDownloadFile_WrongHeader(t *testing.T) {
 	require.Contains(t, err.Error(), "filename missing")
 }
 
+func TestDownloadFile_PathTraversal(t *testing.T) {
+
+	url, fileContent, downloadDir := testSetupDownloadFile(t, "", func(string) string {
+		return `attachment; filename="../../evil.txt"`
+	})
+
+	resp, err := New(context.Background(), url).Get()
+	require.NoError(t, err)
+
+	// The file is saved under downloadDir with its base name
+	_, filePath, err := resp.DownloadFile(downloadDir)
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join(downloadDir, "evil.txt"), filePath)
+	t.Cleanup(func() { _ = os.Remove(filePath) })
+
+	data, err := ioutil.ReadFile(filePath)
+	require.NoError(t, err)
+	require.Equal(t, fileContent, string(data))
+
+	for _, name := range []string{"..", ".", "/"} {
+		url, _, downloadDir = testSetupDownloadFile(t, "", func(string) string {
+			return `attachment; filename="` + name + `"`
+		})
+
+		resp, err = New(context.Background(), url).Get()
+		require.NoError(t, err)
+
+		_, _, err = resp.DownloadFile(downloadDir)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid filename")
+	}
+}
+
+func TestGunzipDownloads(t *testing.T) {
+	compressed := gzipBytes(t, []byte(responseData))
+
+	// Start a local HTTP server sending a gzip encoded file
+	server := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			rw.Header().Set("Content-Disposition", `attachment; filename="data.txt.gz"`)
+			rw.Header().Set("Content-Encoding", "gzip")
+			_, _ = rw.Write(compressed)
+		}),
+	)
+	defer server.Close()
+
+	dir := t.TempDir()
+
+	// Decompressed by the package or by net/http
+	for _, r := range []*Req{
+		New(context.Background(), server.URL).DisableAutoDecompress().GunzipDownloads(),
+		New(context.Background(), server.URL).GunzipDownloads(),
+	} {
+		resp, err := r.Get()
+		require.NoError(t, err)
+
+		_, filePath, err := resp.DownloadFile(dir)
+		require.NoError(t, err)
+		require.Equal(t, filepath.Join(dir, "data.txt"), filePath)
+
+		data, err := ioutil.ReadFile(filePath)
+		require.NoError(t, err)
+		require.Equal(t, responseData, string(data))
+	}
+
+	// Raw downloads are kept
+	resp, err := New(context.Background(), server.URL).DisableAutoDecompress().Get()
+	require.NoError(t, err)
+
+	_, filePath, err := resp.DownloadFile(dir)
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join(dir, "data.txt.gz"), filePath)
+
+	data, err := ioutil.ReadFile(filePath)
+	require.NoError(t, err)
+	require.Equal(t, compressed, data)
+}
+
 func testSetupDownloadFile(t *testing.T, contentType string, contentDisp func(string) string) (url, fileContent, downloadDir string) {
 	t.Helper()
 
@@ -271,3 +518,750 @@ func testSetupDownloadFile(t *testing.T, contentType string, contentDisp func(st
 
 	return server.URL, fileContent, downloadDir
 }
+
+func TestTeeBody(t *testing.T) {
+
+	// Start a local HTTP server
+	server := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			_, err := rw.Write([]byte(responseData))
+			require.NoError(t, err)
+		}),
+	)
+	defer server.Close()
+
+	resp, err := New(context.Background(), server.URL).Get()
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	body, err := resp.TeeBody(&buf)
+	require.NoError(t, err)
+	require.Equal(t, responseData, string(body))
+	require.Equal(t, responseData, buf.String())
+
+	// Cached body is written on subsequent calls
+	buf.Reset()
+	body, err = resp.TeeBody(&buf)
+	require.NoError(t, err)
+	require.Equal(t, responseData, string(body))
+	require.Equal(t, responseData, buf.String())
+}
+
+func TestSetManualClose(t *testing.T) {
+
+	// Start a local HTTP server
+	server := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			_, err := rw.Write([]byte(responseData))
+			require.NoError(t, err)
+		}),
+	)
+	defer server.Close()
+
+	resp, err := New(context.Background(), server.URL).SetManualClose().Get()
+	require.NoError(t, err)
+
+	// Partial read, then the rest
+	reader, err := resp.Reader()
+	require.NoError(t, err)
+	prefix := make([]byte, 5)
+	_, err = io.ReadFull(reader, prefix)
+	require.NoError(t, err)
+
+	rest, err := resp.Body()
+	require.NoError(t, err)
+	require.Equal(t, responseData, string(prefix)+string(rest))
+
+	// The body is still open until closed by the caller
+	_, err = resp.Response().Body.Read(make([]byte, 1))
+	require.Equal(t, io.EOF, err)
+
+	require.NoError(t, resp.Close())
+	_, err = resp.Response().Body.Read(make([]byte, 1))
+	require.Error(t, err)
+	require.NotEqual(t, io.EOF, err)
+
+	// Closed after reading by default
+	resp, err = New(context.Background(), server.URL).Get()
+	require.NoError(t, err)
+	_, err = resp.Body()
+	require.NoError(t, err)
+	_, err = resp.Response().Body.Read(make([]byte, 1))
+	require.NotEqual(t, io.EOF, err)
+}
+
+func TestCookies(t *testing.T) {
+
+	// Start a local HTTP server
+	server := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			http.SetCookie(rw, &http.Cookie{Name: "session", Value: "abc", HttpOnly: true})
+			http.SetCookie(rw, &http.Cookie{Name: "theme", Value: "dark", Path: "/"})
+			http.SetCookie(rw, &http.Cookie{Name: "lang", Value: "en", MaxAge: 3600})
+		}),
+	)
+	defer server.Close()
+
+	resp, err := New(context.Background(), server.URL).Get()
+	require.NoError(t, err)
+	require.Len(t, resp.Headers().Values("Set-Cookie"), 3)
+
+	cookies := resp.Cookies()
+	require.Len(t, cookies, 3)
+	require.Equal(t, "session", cookies[0].Name)
+	require.Equal(t, "abc", cookies[0].Value)
+	require.True(t, cookies[0].HttpOnly)
+	require.Equal(t, "theme", cookies[1].Name)
+	require.Equal(t, "/", cookies[1].Path)
+	require.Equal(t, "lang", cookies[2].Name)
+	require.Equal(t, 3600, cookies[2].MaxAge)
+
+	var nilResp *Response
+	require.Nil(t, nilResp.Cookies())
+}
+
+func TestCookiesTrailer(t *testing.T) {
+
+	// Start a local HTTP server setting a cookie in the trailer
+	server := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			http.SetCookie(rw, &http.Cookie{Name: "session", Value: "abc"})
+			rw.Header().Set("Trailer", "Set-Cookie")
+			_, _ = rw.Write([]byte(responseData))
+			rw.(http.Flusher).Flush()
+			rw.Header().Set("Set-Cookie", (&http.Cookie{Name: "checksum", Value: "123"}).String())
+		}),
+	)
+	defer server.Close()
+
+	resp, err := New(context.Background(), server.URL).Get()
+	require.NoError(t, err)
+	require.Len(t, resp.Cookies(), 1)
+
+	_, err = resp.Body()
+	require.NoError(t, err)
+
+	cookies := resp.Cookies()
+	require.Len(t, cookies, 2)
+	require.Equal(t, "session", cookies[0].Name)
+	require.Equal(t, "checksum", cookies[1].Name)
+	require.Equal(t, "123", cookies[1].Value)
+}
+
+func TestBodyContext(t *testing.T) {
+
+	// Start a local HTTP server which stalls after the first bytes
+	server := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			_, _ = rw.Write([]byte("partial"))
+			rw.(http.Flusher).Flush()
+			if req.URL.Path == "/stall" {
+				select {
+				case <-req.Context().Done():
+				case <-time.After(5 * time.Second):
+				}
+			}
+		}),
+	)
+	defer server.Close()
+
+	resp, err := New(context.Background(), server.URL+"/stall").Get()
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err = resp.BodyContext(ctx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Less(t, int64(time.Since(start)), int64(time.Second))
+
+	// Completed reads are cached
+	resp, err = New(context.Background(), server.URL).Get()
+	require.NoError(t, err)
+
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	body, err := resp.BodyContext(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "partial", string(body))
+
+	body, err = resp.Body()
+	require.NoError(t, err)
+	require.Equal(t, "partial", string(body))
+}
+
+func TestVerifyContentLength(t *testing.T) {
+
+	// Start a local HTTP server which declares a longer body than it sends for /truncated
+	server := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			if req.URL.Path == "/truncated" {
+				rw.Header().Set("Content-Length", "100")
+			}
+			_, _ = rw.Write([]byte(responseData))
+		}),
+	)
+	defer server.Close()
+
+	resp, err := New(context.Background(), server.URL).Get()
+	require.NoError(t, err)
+	require.NoError(t, resp.VerifyContentLength())
+
+	resp, err = New(context.Background(), server.URL+"/truncated").Get()
+	require.NoError(t, err)
+	require.ErrorIs(t, resp.VerifyContentLength(), ErrContentLengthMismatch)
+
+	resp, err = New(context.Background(), server.URL+"/truncated").Get()
+	require.NoError(t, err)
+	require.ErrorIs(t, resp.SaveFile(filepath.Join(t.TempDir(), "file")), ErrContentLengthMismatch)
+
+	// Also when the body is streamed to the file
+	resp, err = New(context.Background(), server.URL+"/truncated").SetMaxResponseSize(1 << 20).Get()
+	require.NoError(t, err)
+	require.ErrorIs(t, resp.SaveFile(filepath.Join(t.TempDir(), "file")), ErrContentLengthMismatch)
+
+	// Longer bodies, net/http doesn't allow them so the response is built by hand
+	resp = NewResponse(&http.Response{ContentLength: 2, Body: ioutil.NopCloser(strings.NewReader("body"))})
+	require.ErrorIs(t, resp.VerifyContentLength(), ErrContentLengthMismatch)
+
+	// Unknown length
+	resp = NewResponse(&http.Response{ContentLength: -1, Body: ioutil.NopCloser(strings.NewReader("body"))})
+	require.NoError(t, resp.VerifyContentLength())
+}
+
+func TestDecodeJSON(t *testing.T) {
+
+	// Start a local HTTP server
+	server := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			if req.URL.Path == "/latin1" {
+				rw.Header().Set("Content-Type", "application/json; charset=ISO-8859-1")
+
+				// "Jöhn" in ISO-8859-1
+				_, err := rw.Write([]byte("{\"first_name\":\"J\xf6hn\",\"age\":42}"))
+				require.NoError(t, err)
+				return
+			}
+
+			// ASCII bodies decode the same in any charset
+			rw.Header().Set("Content-Type", "application/json; charset=windows-1252")
+			_, err := rw.Write([]byte(`{"first_name":"John","age":42}`))
+			require.NoError(t, err)
+		}),
+	)
+	defer server.Close()
+
+	resp, err := New(context.Background(), server.URL+"/latin1").Get()
+	require.NoError(t, err)
+
+	var data Data
+	require.NoError(t, resp.DecodeJSON(&data))
+	require.Equal(t, "Jöhn", data.FirstName)
+	require.Equal(t, 42, data.Age)
+
+	resp, err = New(context.Background(), server.URL).Get()
+	require.NoError(t, err)
+
+	require.NoError(t, resp.DecodeJSON(&data))
+	require.Equal(t, "John", data.FirstName)
+}
+
+func TestStreamJSONArray(t *testing.T) {
+	const count = 10000
+
+	// Start a local HTTP server streaming a large array
+	server := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			rw.Header().Set("Content-Type", "application/json")
+			_, _ = rw.Write([]byte("["))
+			for i := 0; i < count; i++ {
+				if i > 0 {
+					_, _ = rw.Write([]byte(","))
+				}
+				_, _ = fmt.Fprintf(rw, `{"first_name":"John","age":%d}`, i)
+			}
+			_, _ = rw.Write([]byte("]"))
+		}),
+	)
+	defer server.Close()
+
+	resp, err := New(context.Background(), server.URL).Get()
+	require.NoError(t, err)
+
+	out := make(chan json.RawMessage)
+	errs := make(chan error, 1)
+	go func() {
+		errs <- resp.StreamJSONArray(out)
+	}()
+
+	i := 0
+	for element := range out {
+		var data Data
+		require.NoError(t, json.Unmarshal(element, &data))
+		require.Equal(t, i, data.Age)
+		i++
+	}
+	require.NoError(t, <-errs)
+	require.Equal(t, count, i)
+
+	// Not an array and truncated arrays
+	for _, body := range []string{`{"a":1}`, `[1,2`} {
+		out = make(chan json.RawMessage, 10)
+		err = NewResponse(&http.Response{Body: ioutil.NopCloser(strings.NewReader(body))}).StreamJSONArray(out)
+		require.Error(t, err, body)
+	}
+	require.Len(t, out, 2)
+}
+
+func TestSetJSONDecoderOptions(t *testing.T) {
+
+	// Start a local HTTP server
+	server := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			rw.Header().Set("Content-Type", "application/json")
+			_, err := rw.Write([]byte(`{"first_name":"John","age":42,"id":12345678901234567890}`))
+			require.NoError(t, err)
+		}),
+	)
+	defer server.Close()
+
+	// Unknown fields are ignored by default
+	resp, err := New(context.Background(), server.URL).Get()
+	require.NoError(t, err)
+
+	var data Data
+	require.NoError(t, resp.DecodeJSON(&data))
+	require.Equal(t, "John", data.FirstName)
+
+	resp, err = New(context.Background(), server.URL).SetJSONDecoderOptions(true, false).Get()
+	require.NoError(t, err)
+	require.Error(t, resp.DecodeJSON(&data))
+
+	// Numbers keep their precision
+	resp, err = New(context.Background(), server.URL).SetJSONDecoderOptions(false, true).Get()
+	require.NoError(t, err)
+
+	var m map[string]interface{}
+	require.NoError(t, resp.DecodeJSON(&m))
+	require.Equal(t, json.Number("12345678901234567890"), m["id"])
+	require.Equal(t, json.Number("42"), m["age"])
+
+	// Trailing data is rejected like without options
+	require.Error(t, NewResponse(&http.Response{Body: ioutil.NopCloser(strings.NewReader(`{} x`))}).DecodeJSON(&m))
+	resp = NewResponse(&http.Response{Body: ioutil.NopCloser(strings.NewReader(`{} x`))})
+	resp.jsonUseNumber = true
+	require.Error(t, resp.DecodeJSON(&m))
+}
+
+func TestBodyJSONPretty(t *testing.T) {
+	resp := NewResponse(&http.Response{Body: ioutil.NopCloser(strings.NewReader(`{"b":[1,2],"a":{"c":1.50}}`))})
+
+	pretty, err := resp.BodyJSONPretty()
+	require.NoError(t, err)
+	require.Equal(t, "{\n  \"b\": [\n    1,\n    2\n  ],\n  \"a\": {\n    \"c\": 1.50\n  }\n}", pretty)
+
+	resp = NewResponse(&http.Response{Body: ioutil.NopCloser(strings.NewReader(`<html>`))})
+	_, err = resp.BodyJSONPretty()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "response body is not valid JSON")
+}
+
+func TestDecodeXML(t *testing.T) {
+
+	type person struct {
+		Name string `xml:"name"`
+	}
+
+	// "Jöhn" in ISO-8859-1
+	document := "<?xml version=\"1.0\" encoding=\"ISO-8859-1\"?><person><name>J\xf6hn</name></person>"
+
+	var table = []struct {
+		name        string
+		contentType string
+	}{
+		{"content type charset", "application/xml; charset=ISO-8859-1"},
+		{"xml declaration", "application/xml"},
+	}
+
+	for _, row := range table {
+		t.Run(row.name, func(t *testing.T) {
+
+			// Start a local HTTP server
+			server := httptest.NewServer(
+				http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+					rw.Header().Set("Content-Type", row.contentType)
+					_, err := rw.Write([]byte(document))
+					require.NoError(t, err)
+				}),
+			)
+			defer server.Close()
+
+			resp, err := New(context.Background(), server.URL).Get()
+			require.NoError(t, err)
+
+			var p person
+			require.NoError(t, resp.DecodeXML(&p))
+			require.Equal(t, "Jöhn", p.Name)
+		})
+	}
+}
+
+func TestString(t *testing.T) {
+	resp := &Response{
+		resp: &http.Response{
+			Header: http.Header{"Content-Type": []string{"text/plain; charset=latin1"}},
+			Body:   ioutil.NopCloser(bytes.NewBufferString("caf\xe9")),
+		},
+	}
+
+	s, err := resp.String()
+	require.NoError(t, err)
+	require.Equal(t, "café", s)
+
+	resp = &Response{
+		resp: &http.Response{
+			Header: http.Header{"Content-Type": []string{"text/plain; charset=koi8-r"}},
+			Body:   ioutil.NopCloser(bytes.NewBufferString("\xf0\xd2\xc9\xd7\xc5\xd4")),
+		},
+	}
+
+	s, err = resp.String()
+	require.NoError(t, err)
+	require.Equal(t, "Привет", s)
+
+	// Unknown charsets are returned as is
+	resp = &Response{
+		resp: &http.Response{
+			Header: http.Header{"Content-Type": []string{"text/plain; charset=x-unknown"}},
+			Body:   ioutil.NopCloser(bytes.NewBufferString("hello")),
+		},
+	}
+
+	s, err = resp.String()
+	require.NoError(t, err)
+	require.Equal(t, "hello", s)
+}
+
+// countingReadCloser counts the bytes read from the wrapped io.ReadCloser
+type countingReadCloser struct {
+	io.ReadCloser
+	n int
+}
+
+func (c *countingReadCloser) Read(p []byte) (int, error) {
+	n, err := c.ReadCloser.Read(p)
+	c.n += n
+	return n, err
+}
+
+func TestDecodeJSONReadsBodyOnce(t *testing.T) {
+	body := &countingReadCloser{ReadCloser: ioutil.NopCloser(bytes.NewBufferString(`{"first_name":"John","age":42}`))}
+	resp := &Response{resp: &http.Response{Body: body}}
+
+	var first Data
+	require.NoError(t, resp.DecodeJSON(&first))
+	read := body.n
+
+	var second Data
+	require.NoError(t, resp.DecodeJSON(&second))
+	require.Equal(t, read, body.n)
+	require.Equal(t, first, second)
+}
+
+func TestReadBodyEmptyCached(t *testing.T) {
+	body := &countingReadCloser{ReadCloser: ioutil.NopCloser(bytes.NewBuffer(nil))}
+	resp := &Response{resp: &http.Response{Body: body}}
+
+	_, err := resp.Body()
+	require.NoError(t, err)
+
+	// Body is closed after the first read, a second read must use the cache
+	resp.resp.Body = nil
+	data, err := resp.Body()
+	require.NoError(t, err)
+	require.Empty(t, data)
+}
+
+func TestSave(t *testing.T) {
+
+	// Start a local HTTP server
+	server := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			switch req.URL.Path {
+			case "/disposition/url-name.bin":
+				rw.Header().Set("Content-Disposition", `attachment; filename="../disposition-name.bin"`)
+			case "/":
+				rw.Header().Set("Content-Type", "application/json")
+			}
+			_, err := rw.Write([]byte(responseData))
+			require.NoError(t, err)
+		}),
+	)
+	defer server.Close()
+
+	var table = []struct {
+		name     string
+		path     string
+		expected string
+	}{
+		{"content disposition", "/disposition/url-name.bin", "disposition-name.bin"},
+		{"url", "/files/url-name.bin", "url-name.bin"},
+		{"content type", "/", "download.json"},
+	}
+
+	for _, row := range table {
+		t.Run(row.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "httpreq-save-*")
+			require.NoError(t, err)
+			defer os.RemoveAll(dir)
+
+			resp, err := New(context.Background(), server.URL+row.path).Get()
+			require.NoError(t, err)
+
+			filePath, err := resp.Save(dir)
+			require.NoError(t, err)
+			require.Equal(t, filepath.Join(dir, row.expected), filePath)
+
+			data, err := ioutil.ReadFile(filePath)
+			require.NoError(t, err)
+			require.Equal(t, responseData, string(data))
+		})
+	}
+}
+
+// countingListener counts the bytes of accepted connections
+type countingListener struct {
+	net.Listener
+	counter *byteCounter
+}
+
+func (l *countingListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return &countingConn{Conn: conn, counter: l.counter}, nil
+}
+
+func TestBytesReadWritten(t *testing.T) {
+	content := randStringBytes(64 * 1024)
+
+	// Start a local HTTP server counting bytes on the server side
+	server := httptest.NewUnstartedServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			_, err := rw.Write([]byte(content))
+			require.NoError(t, err)
+		}),
+	)
+	serverCounter := &byteCounter{}
+	server.Listener = &countingListener{Listener: server.Listener, counter: serverCounter}
+	server.Start()
+	defer server.Close()
+
+	r := New(context.Background(), server.URL).CountBytes()
+
+	for i := 0; i < 2; i++ {
+		serverRead, serverWritten := serverCounter.Read(), serverCounter.Written()
+
+		resp, err := r.Get()
+		require.NoError(t, err)
+
+		body, err := resp.Body()
+		require.NoError(t, err)
+		require.Equal(t, content, string(body))
+
+		require.Greater(t, resp.BytesRead(), int64(len(content)))
+		require.Equal(t, serverCounter.Written()-serverWritten, resp.BytesRead())
+		require.Equal(t, serverCounter.Read()-serverRead, resp.BytesWritten())
+	}
+
+	// Counting is disabled by default
+	resp, err := New(context.Background(), server.URL).Get()
+	require.NoError(t, err)
+	require.Equal(t, int64(0), resp.BytesRead())
+	require.Equal(t, int64(0), resp.BytesWritten())
+}
+
+func TestBodyEquals(t *testing.T) {
+	resp := &Response{resp: &http.Response{Body: ioutil.NopCloser(bytes.NewBufferString(responseData))}}
+
+	ok, err := resp.BodyEquals([]byte(responseData))
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	ok, err = resp.BodyEquals([]byte("something else"))
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	_, err = (&Response{}).BodyEquals(nil)
+	require.Error(t, err)
+}
+
+func TestBodyContains(t *testing.T) {
+	resp := &Response{resp: &http.Response{Body: ioutil.NopCloser(bytes.NewBufferString(responseData))}}
+
+	ok, err := resp.BodyContains(`"data": "done!"`)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	ok, err = resp.BodyContains("failed")
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	_, err = (&Response{}).BodyContains("")
+	require.Error(t, err)
+}
+
+func TestBodySeeker(t *testing.T) {
+	resp := &Response{resp: &http.Response{Body: ioutil.NopCloser(bytes.NewBufferString(responseData))}}
+
+	rs, err := resp.BodySeeker()
+	require.NoError(t, err)
+
+	first, err := ioutil.ReadAll(rs)
+	require.NoError(t, err)
+	require.Equal(t, responseData, string(first))
+
+	_, err = rs.Seek(0, io.SeekStart)
+	require.NoError(t, err)
+
+	second, err := ioutil.ReadAll(rs)
+	require.NoError(t, err)
+	require.Equal(t, first, second)
+
+	_, err = (&Response{}).BodySeeker()
+	require.Error(t, err)
+}
+
+func TestSetDoNotBuffer(t *testing.T) {
+
+	// Start a local HTTP server
+	server := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			_, err := rw.Write([]byte(responseData))
+			require.NoError(t, err)
+		}),
+	)
+	defer server.Close()
+
+	resp, err := New(context.Background(), server.URL).SetDoNotBuffer().Get()
+	require.NoError(t, err)
+
+	_, err = resp.Body()
+	require.Equal(t, ErrBufferingDisabled, err)
+
+	var data Data
+	require.Equal(t, ErrBufferingDisabled, resp.DecodeJSON(&data))
+
+	// Body can be streamed
+	reader, err := resp.Reader()
+	require.NoError(t, err)
+	body, err := ioutil.ReadAll(reader)
+	require.NoError(t, err)
+	require.Equal(t, responseData, string(body))
+	require.NoError(t, reader.Close())
+}
+
+func TestReader(t *testing.T) {
+	_, err := (&Response{}).Reader()
+	require.Error(t, err)
+
+	// Cached body is returned after it's read
+	resp := &Response{resp: &http.Response{Body: ioutil.NopCloser(bytes.NewBufferString(responseData))}}
+	_, err = resp.Body()
+	require.NoError(t, err)
+
+	reader, err := resp.Reader()
+	require.NoError(t, err)
+	body, err := ioutil.ReadAll(reader)
+	require.NoError(t, err)
+	require.Equal(t, responseData, string(body))
+}
+
+func TestSaveFileAndHash(t *testing.T) {
+	content := randStringBytes(4096)
+
+	// Start a local HTTP server
+	server := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			_, err := rw.Write([]byte(content))
+			require.NoError(t, err)
+		}),
+	)
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "httpreq-hash-*")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	for _, algo := range []string{"md5", "sha1", "sha256", "sha512"} {
+		resp, err := New(context.Background(), server.URL).Get()
+		require.NoError(t, err)
+
+		filePath := filepath.Join(dir, algo)
+		digest, err := resp.SaveFileAndHash(filePath, algo)
+		require.NoError(t, err)
+
+		data, err := ioutil.ReadFile(filePath)
+		require.NoError(t, err)
+		require.Equal(t, content, string(data))
+
+		h, err := newHash(algo)
+		require.NoError(t, err)
+		_, err = h.Write(data)
+		require.NoError(t, err)
+		require.Equal(t, hex.EncodeToString(h.Sum(nil)), digest)
+	}
+
+	resp, err := New(context.Background(), server.URL).Get()
+	require.NoError(t, err)
+	_, err = resp.SaveFileAndHash(filepath.Join(dir, "crc"), "crc32")
+	require.Error(t, err)
+}
+
+func TestClone(t *testing.T) {
+
+	// Start a local HTTP server
+	server := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			rw.Header().Set("Test-Header", "this is response")
+			_, err := rw.Write([]byte(responseData))
+			require.NoError(t, err)
+		}),
+	)
+	defer server.Close()
+
+	resp, err := New(context.Background(), server.URL).Get()
+	require.NoError(t, err)
+
+	clone, err := resp.Clone()
+	require.NoError(t, err)
+	require.NotSame(t, resp, clone)
+	require.Equal(t, "this is response", clone.Headers().Get("Test-Header"))
+	require.Equal(t, resp.StatusCode(), clone.StatusCode())
+
+	// Both copies read the body independently
+	var wg sync.WaitGroup
+	for _, r := range []*Response{resp, clone} {
+		wg.Add(1)
+		go func(r *Response) {
+			defer wg.Done()
+
+			reader, err := r.Reader()
+			require.NoError(t, err)
+			body, err := ioutil.ReadAll(reader)
+			require.NoError(t, err)
+			require.Equal(t, responseData, string(body))
+		}(r)
+	}
+	wg.Wait()
+
+	body, err := clone.Body()
+	require.NoError(t, err)
+	require.Equal(t, responseData, string(body))
+
+	_, err = (&Response{}).Clone()
+	require.Error(t, err)
+}
@@ -0,0 +1,46 @@
+package httpreq
+
+// Result is a Response with the error of sending the request, for fluent one-liners like
+//
+//	err := httpreq.New(ctx, url).DoResult(http.MethodGet).JSON(&v)
+type Result struct {
+	// Response is the response, it may be set with an error, e.g. with FailOnError
+	Response *Response
+
+	// Err is the error of sending the request
+	Err error
+}
+
+// DoResult sends the request with method and returns its Result
+func (r *Req) DoResult(method string) Result {
+	resp, err := r.send(method)
+	return Result{Response: resp, Err: err}
+}
+
+// JSON decodes the JSON response body into v and closes the response, it returns Err if set
+func (r Result) JSON(v interface{}) error {
+	if r.Err != nil {
+		r.Response.Close()
+		return r.Err
+	}
+	defer r.Response.Close()
+	return r.Response.DecodeJSON(v)
+}
+
+// Body returns the response body and closes the response, it returns Err if set
+func (r Result) Body() ([]byte, error) {
+	if r.Err != nil {
+		r.Response.Close()
+		return nil, r.Err
+	}
+	defer r.Response.Close()
+	return r.Response.Body()
+}
+
+// Must returns the Response and panics if Err is set, for scripts and tests
+func (r Result) Must() *Response {
+	if r.Err != nil {
+		panic(r.Err)
+	}
+	return r.Response
+}
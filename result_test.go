@@ -0,0 +1,51 @@
+package httpreq
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestResult(t *testing.T) {
+
+	// Start a local HTTP server
+	server := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			if req.URL.Path == "/missing" {
+				rw.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_, _ = rw.Write([]byte(`{"name":"value"}`))
+		}),
+	)
+	defer server.Close()
+
+	var v struct {
+		Name string `json:"name"`
+	}
+	require.NoError(t, New(context.Background(), server.URL).DoResult(http.MethodGet).JSON(&v))
+	require.Equal(t, "value", v.Name)
+
+	body, err := New(context.Background(), server.URL).DoResult(http.MethodGet).Body()
+	require.NoError(t, err)
+	require.Equal(t, `{"name":"value"}`, string(body))
+
+	resp := New(context.Background(), server.URL).DoResult(http.MethodGet).Must()
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+
+	// Errors
+	result := New(context.Background(), server.URL+"/missing").FailOnError().DoResult(http.MethodGet)
+	require.Error(t, result.Err)
+	require.Equal(t, http.StatusNotFound, result.Response.StatusCode())
+	require.Error(t, result.JSON(&v))
+
+	_, err = New(context.Background(), "wrong-host").DoResult(http.MethodGet).Body()
+	require.Error(t, err)
+
+	require.Panics(t, func() {
+		New(context.Background(), "wrong-host").DoResult(http.MethodGet).Must()
+	})
+}
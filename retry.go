@@ -0,0 +1,163 @@
+package httpreq
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+// SetRetry retries failed requests up to maxRetries times. Requests failing with an error
+// or responded with 429 or 5xx are retried after wait, which doubles for each further retry.
+// With SetSuccessFunc, responses are retried when it reports a failure instead.
+// Request bodies are sent again, so they must be replayable (SetBody, SetBodyFunc...).
+// POST and PATCH requests aren't retried unless AllowRetryOnPost is set.
+func (r *Req) SetRetry(maxRetries int, wait time.Duration) *Req {
+	r.maxRetries = maxRetries
+	r.retryWait = wait
+	return r
+}
+
+// SetRetryJitter adds a random duration up to fraction of the wait to each retry wait, so clients
+// failing at the same time don't retry in lockstep. i.e. 0.5 waits between 1 and 1.5 times the wait.
+func (r *Req) SetRetryJitter(fraction float64) *Req {
+	r.retryJitter = fraction
+	return r
+}
+
+// SetRetryRandSource sets the source of the retry jitter, so tests and reproducible runs
+// get the same waits. By default a source seeded with the time is used.
+func (r *Req) SetRetryRandSource(src rand.Source) *Req {
+	r.retryRandSource = src
+	return r
+}
+
+// OnRetry sets a function called before waiting for each retry with the number of the failed
+// attempt starting at 1 and its response or error, i.e. to log or count retries. The response
+// body can be read, it's closed after the function returns.
+func (r *Req) OnRetry(f func(attempt int, resp *Response, err error)) *Req {
+	r.onRetry = f
+	return r
+}
+
+// AllowRetryOnPost allows retrying POST and PATCH requests, which may apply their side effects
+// more than once. Servers supporting it can deduplicate them with AutoIdempotencyKey.
+func (r *Req) AllowRetryOnPost() *Req {
+	r.retryOnPost = true
+	return r
+}
+
+// SetAttemptTimeout bounds each attempt of a request including reading its response body,
+// while SetDeadline still bounds the request with all its retries and waits
+func (r *Req) SetAttemptTimeout(d time.Duration) *Req {
+	r.attemptTimeout = d
+	return r
+}
+
+// retry sends the request, retrying as configured by SetRetry
+func (r *Req) retry(req *http.Request) (*http.Response, error) {
+	wait := r.retryWait
+
+	var rnd *rand.Rand
+	if r.retryJitter > 0 {
+		src := r.retryRandSource
+		if src == nil {
+			src = rand.NewSource(r.clock.Now().UnixNano())
+		}
+		rnd = rand.New(src)
+	}
+
+	for attempt := 0; ; attempt++ {
+		attemptReq := req
+		if attempt > 0 {
+			attemptReq = req.Clone(req.Context())
+			if req.GetBody != nil {
+				body, err := req.GetBody()
+				if err != nil {
+					return nil, err
+				}
+				attemptReq.Body = body
+			}
+		}
+
+		resp, err := r.attempt(attemptReq)
+		if attempt >= r.maxRetries || !r.shouldRetry(req, resp, err) {
+			return resp, err
+		}
+
+		if err != nil {
+			r.log().Warnf("Retrying HTTP request: %s, %v", req.URL, err)
+		} else {
+			r.log().Warnf("Retrying HTTP request: %s, status %d", req.URL, resp.StatusCode)
+		}
+
+		if r.onRetry != nil {
+			var response *Response
+			if resp != nil {
+				response = &Response{resp: resp, logger: r.log()}
+			}
+			r.onRetry(attempt+1, response, err)
+		}
+
+		if resp != nil {
+			drainBody(resp.Body)
+		}
+
+		delay := wait
+		if rnd != nil {
+			delay += time.Duration(rnd.Float64() * r.retryJitter * float64(wait))
+		}
+
+		if err := r.clock.Sleep(req.Context(), delay); err != nil {
+			return nil, err
+		}
+		wait *= 2
+	}
+}
+
+// attempt sends the request once, bounded by the attempt timeout if set
+func (r *Req) attempt(req *http.Request) (*http.Response, error) {
+	if r.attemptTimeout <= 0 {
+		return r.roundTrip(req)
+	}
+
+	// The attempt context is canceled when the response body is closed
+	ctx, cancel := context.WithTimeout(req.Context(), r.attemptTimeout)
+
+	resp, err := r.roundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	resp.Body = &cancelBody{ReadCloser: resp.Body, ctx: ctx, cancel: cancel}
+
+	return resp, nil
+}
+
+// shouldRetry reports whether an attempt of req failed and can be retried
+func (r *Req) shouldRetry(req *http.Request, resp *http.Response, err error) bool {
+
+	// The request itself is canceled or out of time
+	if req.Context().Err() != nil {
+		return false
+	}
+
+	// The request isn't idempotent
+	if !r.retryOnPost && (req.Method == http.MethodPost || req.Method == http.MethodPatch) {
+		return false
+	}
+
+	// The body can't be sent again
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+
+	if err != nil {
+		return true
+	}
+	if r.successFunc != nil {
+		return !r.succeededRaw(resp)
+	}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+}
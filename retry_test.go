@@ -0,0 +1,259 @@
+package httpreq
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetRetry(t *testing.T) {
+	var requests int32
+
+	// Start a local HTTP server which is unavailable for the first two requests
+	server := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			body, err := ioutil.ReadAll(req.Body)
+			require.NoError(t, err)
+			require.Equal(t, "body", string(body))
+
+			if atomic.AddInt32(&requests, 1) <= 2 {
+				rw.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			_, err = rw.Write([]byte(responseData))
+			require.NoError(t, err)
+		}),
+	)
+	defer server.Close()
+
+	resp, err := New(context.Background(), server.URL).SetRetry(2, 10*time.Millisecond).SetBody([]byte("body")).Put()
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+	require.Equal(t, int32(3), atomic.LoadInt32(&requests))
+
+	body, err := resp.Body()
+	require.NoError(t, err)
+	require.Equal(t, responseData, string(body))
+
+	// The last response is returned when retries are exhausted
+	atomic.StoreInt32(&requests, 0)
+	resp, err = New(context.Background(), server.URL).SetRetry(1, 10*time.Millisecond).SetBody([]byte("body")).Put()
+	require.NoError(t, err)
+	require.Equal(t, http.StatusServiceUnavailable, resp.StatusCode())
+	require.Equal(t, int32(2), atomic.LoadInt32(&requests))
+
+	// Bodies of incoming requests are sent once, the response isn't retried
+	atomic.StoreInt32(&requests, 0)
+	in := httptest.NewRequest(http.MethodPut, "/", strings.NewReader("body"))
+	resp, err = New(context.Background(), server.URL).SetRetry(2, 10*time.Millisecond).SetBodyFromRequest(in).Put()
+	require.NoError(t, err)
+	require.Equal(t, http.StatusServiceUnavailable, resp.StatusCode())
+	require.Equal(t, int32(1), atomic.LoadInt32(&requests))
+}
+
+// fakeClock records sleeps and advances its time instead of waiting
+type fakeClock struct {
+	now    time.Time
+	sleeps []time.Duration
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) Sleep(ctx context.Context, d time.Duration) error {
+	c.sleeps = append(c.sleeps, d)
+	c.now = c.now.Add(d)
+	return ctx.Err()
+}
+
+func TestRetryBackoff(t *testing.T) {
+
+	// Start a local HTTP server which is always unavailable
+	server := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			rw.WriteHeader(http.StatusServiceUnavailable)
+		}),
+	)
+	defer server.Close()
+
+	clock := &fakeClock{now: time.Now()}
+
+	start := time.Now()
+	resp, err := New(context.Background(), server.URL).setClock(clock).SetRetry(4, time.Minute).Get()
+	require.NoError(t, err)
+	require.Equal(t, http.StatusServiceUnavailable, resp.StatusCode())
+
+	// The wait doubles without really waiting
+	require.Equal(t, []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute, 8 * time.Minute}, clock.sleeps)
+	require.Less(t, int64(time.Since(start)), int64(time.Second))
+}
+
+func TestSetRetryRandSource(t *testing.T) {
+
+	// Start a local HTTP server which is always unavailable
+	server := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			rw.WriteHeader(http.StatusServiceUnavailable)
+		}),
+	)
+	defer server.Close()
+
+	sleeps := func(seed int64) []time.Duration {
+		clock := &fakeClock{now: time.Now()}
+		_, err := New(context.Background(), server.URL).
+			setClock(clock).
+			SetRetry(3, time.Second).
+			SetRetryJitter(0.5).
+			SetRetryRandSource(rand.NewSource(seed)).
+			Get()
+		require.NoError(t, err)
+		return clock.sleeps
+	}
+
+	// The same seed gives the same waits
+	first := sleeps(1)
+	require.Equal(t, first, sleeps(1))
+	require.NotEqual(t, first, sleeps(2))
+
+	// Each wait is between the backoff and 1.5 times it
+	rnd := rand.New(rand.NewSource(1))
+	wait := time.Second
+	for _, d := range first {
+		require.Equal(t, wait+time.Duration(rnd.Float64()*0.5*float64(wait)), d)
+		require.GreaterOrEqual(t, int64(d), int64(wait))
+		require.Less(t, int64(d), int64(wait*3/2))
+		wait *= 2
+	}
+}
+
+func TestOnRetry(t *testing.T) {
+	var requests int32
+
+	// Start a local HTTP server which is unavailable for the first three requests
+	server := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			n := atomic.AddInt32(&requests, 1)
+			if n <= 3 {
+				rw.WriteHeader(http.StatusServiceUnavailable)
+				_, _ = fmt.Fprintf(rw, "attempt %d", n)
+				return
+			}
+			_, _ = rw.Write([]byte(responseData))
+		}),
+	)
+	defer server.Close()
+
+	var attempts []int
+	var bodies []string
+	resp, err := New(context.Background(), server.URL).
+		setClock(&fakeClock{now: time.Now()}).
+		SetRetry(5, time.Second).
+		OnRetry(func(attempt int, resp *Response, err error) {
+			require.NoError(t, err)
+			require.Equal(t, http.StatusServiceUnavailable, resp.StatusCode())
+
+			body, err := resp.Body()
+			require.NoError(t, err)
+
+			attempts = append(attempts, attempt)
+			bodies = append(bodies, string(body))
+		}).
+		Get()
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+
+	require.Equal(t, []int{1, 2, 3}, attempts)
+	require.Equal(t, []string{"attempt 1", "attempt 2", "attempt 3"}, bodies)
+
+	// Errors without a response
+	attempts = nil
+	_, err = New(context.Background(), "http://127.0.0.1:1").
+		setClock(&fakeClock{now: time.Now()}).
+		SetRetry(2, time.Second).
+		OnRetry(func(attempt int, resp *Response, err error) {
+			require.Error(t, err)
+			require.Nil(t, resp)
+			attempts = append(attempts, attempt)
+		}).
+		Get()
+	require.Error(t, err)
+	require.Equal(t, []int{1, 2}, attempts)
+}
+
+func TestAllowRetryOnPost(t *testing.T) {
+	var requests int32
+
+	// Start a local HTTP server which is always unavailable
+	server := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			atomic.AddInt32(&requests, 1)
+			rw.WriteHeader(http.StatusServiceUnavailable)
+		}),
+	)
+	defer server.Close()
+
+	resp, err := New(context.Background(), server.URL).SetRetry(2, time.Millisecond).Get()
+	require.NoError(t, err)
+	require.Equal(t, http.StatusServiceUnavailable, resp.StatusCode())
+	require.Equal(t, int32(3), atomic.LoadInt32(&requests))
+
+	// Not idempotent methods are sent once
+	for _, method := range []string{http.MethodPost, http.MethodPatch} {
+		atomic.StoreInt32(&requests, 0)
+		resp, err = New(context.Background(), server.URL).SetRetry(2, time.Millisecond).SetBody([]byte("body")).send(method)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusServiceUnavailable, resp.StatusCode())
+		require.Equal(t, int32(1), atomic.LoadInt32(&requests))
+	}
+
+	atomic.StoreInt32(&requests, 0)
+	resp, err = New(context.Background(), server.URL).SetRetry(2, time.Millisecond).AllowRetryOnPost().SetBody([]byte("body")).Post()
+	require.NoError(t, err)
+	require.Equal(t, http.StatusServiceUnavailable, resp.StatusCode())
+	require.Equal(t, int32(3), atomic.LoadInt32(&requests))
+}
+
+func TestSetAttemptTimeout(t *testing.T) {
+	var requests int32
+
+	// Start a local HTTP server which hangs on the first request
+	server := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			if atomic.AddInt32(&requests, 1) == 1 {
+				select {
+				case <-req.Context().Done():
+				case <-time.After(5 * time.Second):
+				}
+				return
+			}
+			_, err := rw.Write([]byte(responseData))
+			require.NoError(t, err)
+		}),
+	)
+	defer server.Close()
+
+	start := time.Now()
+	resp, err := New(context.Background(), server.URL).
+		SetDeadline(time.Now().Add(2*time.Second)).
+		SetRetry(2, 10*time.Millisecond).
+		SetAttemptTimeout(100 * time.Millisecond).
+		Get()
+	require.NoError(t, err)
+	require.Less(t, int64(time.Since(start)), int64(time.Second))
+	require.Equal(t, int32(2), atomic.LoadInt32(&requests))
+
+	// The body of the successful attempt is still readable
+	body, err := resp.Body()
+	require.NoError(t, err)
+	require.Equal(t, responseData, string(body))
+}
@@ -0,0 +1,79 @@
+package httpreq
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// flightGroup coalesces identical in-flight GET requests of all requests with EnableSingleflight
+var flightGroup singleflight.Group
+
+// sharedResponse is a response with its body read, shared by coalesced requests
+type sharedResponse struct {
+	resp *http.Response
+	data []byte
+}
+
+// EnableSingleflight coalesces concurrent identical GET requests (same URL and headers) into a
+// single network call and shares the response, which prevents cache stampedes. The request
+// which makes the call is sent with its own settings (client, timeouts...) for all of them.
+// The shared body is read into memory up to SetMaxResponseSize, so requests with
+// SetDoNotBuffer aren't coalesced.
+func (r *Req) EnableSingleflight() *Req {
+	r.singleflight = true
+	return r
+}
+
+// doShared sends req once for all concurrent identical requests
+func (r *Req) doShared(req *http.Request) (*http.Response, error) {
+	v, err, _ := flightGroup.Do(flightKey(req), func() (interface{}, error) {
+		resp, err := r.do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+
+		// Body is read once and given to every request
+		if r.maxResponseSize > 0 && resp.ContentLength > r.maxResponseSize {
+			return nil, ErrResponseTooLarge
+		}
+
+		data, err := ioutil.ReadAll(limitBody(resp.Body, r.maxResponseSize))
+		if err != nil {
+			return nil, err
+		}
+
+		return &sharedResponse{resp: resp, data: data}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	shared := v.(*sharedResponse)
+
+	// Every request gets its own copy of the response
+	resp := *shared.resp
+	resp.Header = shared.resp.Header.Clone()
+	resp.Trailer = shared.resp.Trailer.Clone()
+	resp.Body = ioutil.NopCloser(bytes.NewReader(shared.data))
+
+	return &resp, nil
+}
+
+// flightKey identifies identical requests by method, URL and headers
+func flightKey(req *http.Request) string {
+	var b strings.Builder
+	b.WriteString(req.Method)
+	b.WriteString(" ")
+	b.WriteString(req.URL.String())
+	b.WriteString("\n")
+
+	// Headers are written sorted by key
+	_ = req.Header.Write(&b)
+
+	return b.String()
+}
@@ -0,0 +1,102 @@
+package httpreq
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnableSingleflight(t *testing.T) {
+	var hits int32
+
+	// Start a local HTTP server
+	server := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			atomic.AddInt32(&hits, 1)
+			time.Sleep(300 * time.Millisecond)
+			_, err := rw.Write([]byte(responseData))
+			require.NoError(t, err)
+		}),
+	)
+	defer server.Close()
+
+	const count = 20
+
+	var wg sync.WaitGroup
+	bodies := make([]string, count)
+	errs := make([]error, count)
+
+	for i := 0; i < count; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			resp, err := New(context.Background(), server.URL).EnableSingleflight().Get()
+			if err != nil {
+				errs[i] = err
+				return
+			}
+
+			body, err := resp.Body()
+			bodies[i], errs[i] = string(body), err
+		}(i)
+	}
+	wg.Wait()
+
+	for i := 0; i < count; i++ {
+		require.NoError(t, errs[i])
+		require.Equal(t, responseData, bodies[i])
+	}
+	require.Equal(t, int32(1), atomic.LoadInt32(&hits))
+}
+
+func TestEnableSingleflightLimits(t *testing.T) {
+	var hits int32
+
+	// Start a local HTTP server
+	server := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			atomic.AddInt32(&hits, 1)
+			_, _ = rw.Write([]byte(responseData))
+		}),
+	)
+	defer server.Close()
+
+	_, err := New(context.Background(), server.URL).EnableSingleflight().SetMaxResponseSize(4).Get()
+	require.ErrorIs(t, err, ErrResponseTooLarge)
+
+	// Unbuffered responses are streamed, not shared
+	resp, err := New(context.Background(), server.URL).EnableSingleflight().SetDoNotBuffer().Get()
+	require.NoError(t, err)
+
+	_, err = resp.Body()
+	require.ErrorIs(t, err, ErrBufferingDisabled)
+
+	body, err := resp.Reader()
+	require.NoError(t, err)
+	data, err := ioutil.ReadAll(body)
+	require.NoError(t, err)
+	require.Equal(t, responseData, string(data))
+	require.NoError(t, body.Close())
+	require.Equal(t, int32(2), atomic.LoadInt32(&hits))
+}
+
+func TestFlightKey(t *testing.T) {
+	a, err := New(context.Background(), "http://example.com/a").SetHeaders(map[string]string{"X-Key": "1"}).Request()
+	require.NoError(t, err)
+
+	b, err := New(context.Background(), "http://example.com/a").SetHeaders(map[string]string{"X-Key": "1"}).Request()
+	require.NoError(t, err)
+	require.Equal(t, flightKey(a), flightKey(b))
+
+	c, err := New(context.Background(), "http://example.com/a").SetHeaders(map[string]string{"X-Key": "2"}).Request()
+	require.NoError(t, err)
+	require.NotEqual(t, flightKey(a), flightKey(c))
+}